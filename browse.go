@@ -0,0 +1,194 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// PositionsOp selects record positions in a cluster relative to a given position.
+type PositionsOp int
+
+// List of position lookup operations
+const (
+	PositionsHigher  PositionsOp = iota // positions greater than a given one
+	PositionsLower                      // positions less than a given one
+	PositionsCeiling                    // positions greater than or equal to a given one
+	PositionsFloor                      // positions less than or equal to a given one
+)
+
+func (op PositionsOp) String() string {
+	switch op {
+	case PositionsHigher:
+		return "higher"
+	case PositionsLower:
+		return "lower"
+	case PositionsCeiling:
+		return "ceiling"
+	case PositionsFloor:
+		return "floor"
+	}
+	return fmt.Sprintf("PositionsOp(%d)", int(op))
+}
+
+// PhysicalPosition describes a record stored in a cluster.
+type PhysicalPosition struct {
+	RID           RID
+	RecordSize    int
+	RecordVersion int
+}
+
+// ClusterPositions returns a chunk of positions of existing records in a cluster, relative to a given position.
+// Positions are sorted in ascending order. Chunk size is chosen by the server.
+func (db *Database) ClusterPositions(clusterName string, pos int64, op PositionsOp) ([]PhysicalPosition, error) {
+	return db.ClusterPositionsContext(context.Background(), clusterName, pos, op)
+}
+
+// ClusterPositionsContext is like ClusterPositions, but allows to cancel the request with a context.
+func (db *Database) ClusterPositionsContext(ctx context.Context, clusterName string, pos int64, op PositionsOp) ([]PhysicalPosition, error) {
+	var out []PhysicalPosition
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		out, err = conn.ClusterPositionsContext(ctx, clusterName, pos, op)
+		return
+	})
+	return out, err
+}
+
+// BrowseCluster returns an iterator over all records of a cluster. Iterator walks real record positions,
+// so holes left by deleted records are skipped. Positions are requested in chunks, and records are loaded by RID
+// without SQL queries, bypassing records cache.
+//
+// Iterator can move in both directions: Next starts from the first record, and Prev starts from the last one.
+func (db *Database) BrowseCluster(name string) *ClusterIterator {
+	return &ClusterIterator{db: db, name: name}
+}
+
+// ClusterIterator iterates over records of a cluster. See Database.BrowseCluster.
+type ClusterIterator struct {
+	db   *Database
+	name string
+
+	rec    ORecord
+	pos    int64 // position of the current record
+	hasPos bool
+	off    bool // iterator moved past the last (or before the first) record
+
+	dirSet bool
+	fwd    bool      // direction of buffered records
+	buf    []ORecord // records loaded in current direction
+	from   int64     // position for the next chunk request
+	incl   bool      // include from position to the next chunk
+	eof    bool      // no more records in current direction
+	err    error
+}
+
+// Next moves iterator to the next record. It returns false at the end of cluster or on error (see Err).
+func (it *ClusterIterator) Next() bool {
+	return it.NextContext(context.Background())
+}
+
+// NextContext is like Next, but allows to cancel the request with a context.
+func (it *ClusterIterator) NextContext(ctx context.Context) bool {
+	return it.move(ctx, true)
+}
+
+// Prev moves iterator to the previous record. It returns false at the beginning of cluster or on error (see Err).
+func (it *ClusterIterator) Prev() bool {
+	return it.PrevContext(context.Background())
+}
+
+// PrevContext is like Prev, but allows to cancel the request with a context.
+func (it *ClusterIterator) PrevContext(ctx context.Context) bool {
+	return it.move(ctx, false)
+}
+
+// Record returns current record.
+func (it *ClusterIterator) Record() ORecord {
+	return it.rec
+}
+
+// Err returns an error that occurred during iteration.
+func (it *ClusterIterator) Err() error {
+	return it.err
+}
+
+func (it *ClusterIterator) move(ctx context.Context, fwd bool) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.dirSet || it.fwd != fwd {
+		it.dirSet, it.fwd = true, fwd
+		it.buf, it.eof = nil, false
+		switch {
+		case it.hasPos:
+			it.from, it.incl = it.pos, it.off
+		case fwd:
+			it.from, it.incl = 0, true
+		default:
+			_, end, err := it.db.GetClusterDataRangeContext(ctx, it.name)
+			if err != nil {
+				it.err = err
+				return false
+			}
+			it.from, it.incl = end, true
+			it.eof = end < 0
+		}
+	}
+	for len(it.buf) == 0 {
+		if it.eof {
+			it.rec, it.off = nil, true
+			return false
+		}
+		if err := it.fetch(ctx); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	it.rec, it.buf = it.buf[0], it.buf[1:]
+	it.pos, it.hasPos, it.off = it.rec.GetIdentity().ClusterPos, true, false
+	return true
+}
+
+// fetch loads next chunk of records in current direction.
+func (it *ClusterIterator) fetch(ctx context.Context) error {
+	var op PositionsOp
+	switch {
+	case it.fwd && it.incl:
+		op = PositionsCeiling
+	case it.fwd:
+		op = PositionsHigher
+	case it.incl:
+		op = PositionsFloor
+	default:
+		op = PositionsLower
+	}
+	positions, err := it.db.ClusterPositionsContext(ctx, it.name, it.from, op)
+	if err != nil {
+		return err
+	} else if len(positions) == 0 {
+		it.eof = true
+		return nil
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].RID.ClusterPos < positions[j].RID.ClusterPos
+	})
+	if !it.fwd {
+		for i, j := 0, len(positions)-1; i < j; i, j = i+1, j-1 {
+			positions[i], positions[j] = positions[j], positions[i]
+		}
+	}
+	it.from, it.incl = positions[len(positions)-1].RID.ClusterPos, false
+	return it.db.withConn(ctx, true, func(conn DBSession) error {
+		for _, p := range positions {
+			rec, err := conn.GetRecordByRIDContext(ctx, p.RID, DefaultFetchPlan, true)
+			if err != nil {
+				return err
+			} else if rec == nil { // record might be deleted after positions were requested
+				continue
+			}
+			it.db.bind(rec)
+			it.buf = append(it.buf, rec)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package orient
+
+import (
+	"context"
+	"testing"
+)
+
+// browseSession serves record positions of a single cluster in small chunks.
+type browseSession struct {
+	*linkSession
+	positions []int64 // sorted
+}
+
+const browseChunk = 3
+
+func (s *browseSession) GetClusterDataRangeContext(ctx context.Context, clusterName string) (begin, end int64, err error) {
+	if len(s.positions) == 0 {
+		return -1, -1, nil
+	}
+	return s.positions[0], s.positions[len(s.positions)-1], nil
+}
+
+func (s *browseSession) ClusterPositionsContext(ctx context.Context, clusterName string, pos int64, op PositionsOp) ([]PhysicalPosition, error) {
+	var out []PhysicalPosition
+	add := func(p int64) { out = append(out, PhysicalPosition{RID: NewRID(7, p), RecordVersion: 1}) }
+	switch op {
+	case PositionsHigher, PositionsCeiling:
+		for _, p := range s.positions {
+			if (p > pos || (op == PositionsCeiling && p == pos)) && len(out) < browseChunk {
+				add(p)
+			}
+		}
+	case PositionsLower, PositionsFloor:
+		for i := len(s.positions) - 1; i >= 0; i-- {
+			if p := s.positions[i]; (p < pos || (op == PositionsFloor && p == pos)) && len(out) < browseChunk {
+				add(p)
+			}
+		}
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 { // server returns positions in ascending order
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out, nil
+}
+
+func TestBrowseCluster(t *testing.T) {
+	sess := &browseSession{linkSession: &linkSession{recs: make(map[RID]*Document)}}
+	for _, p := range []int64{0, 1, 3, 4, 8, 9, 10, 15} {
+		sess.positions = append(sess.positions, p)
+		if p != 9 { // deleted after positions were returned
+			sess.recs[NewRID(7, p)] = NewDocument("V")
+		}
+	}
+	db := newTestDB(t, func() DBSession { return sess })
+
+	collect := func(it *ClusterIterator, n int, fwd bool) (out []int64) {
+		for len(out) < n {
+			var ok bool
+			if fwd {
+				ok = it.Next()
+			} else {
+				ok = it.Prev()
+			}
+			if !ok {
+				break
+			}
+			out = append(out, it.Record().GetIdentity().ClusterPos)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	equal := func(a, b []int64) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	it := db.BrowseCluster("v")
+	if got := collect(it, 100, true); !equal(got, []int64{0, 1, 3, 4, 8, 10, 15}) {
+		t.Fatalf("unexpected forward positions: %v", got)
+	}
+	if got := collect(it, 2, false); !equal(got, []int64{15, 10}) {
+		t.Fatalf("unexpected positions after reaching the end: %v", got)
+	}
+	if got := collect(it, 1, true); !equal(got, []int64{15}) {
+		t.Fatalf("unexpected positions after changing direction: %v", got)
+	}
+	if got := collect(db.BrowseCluster("v"), 100, false); !equal(got, []int64{15, 10, 8, 4, 3, 1, 0}) {
+		t.Fatalf("unexpected backward positions: %v", got)
+	} else if sess.commands != 0 {
+		t.Fatalf("records were loaded with %d SQL commands", sess.commands)
+	}
+}
@@ -0,0 +1,201 @@
+package orient
+
+import (
+	"container/list"
+	"sync"
+)
+
+// recordCacheUser is implemented by sessions that can store loaded records to a shared cache.
+type recordCacheUser interface {
+	SetRecordCache(c *RecordCache)
+}
+
+// cachedRecord is a raw copy of a record. New record instance is created on each cache hit,
+// so callers never share mutable records.
+type cachedRecord struct {
+	rid  RID
+	vers int
+	tp   RecordType
+	data []byte
+	ser  RecordSerializer
+}
+
+// RecordCache is a client-side LRU cache of records. It stores records prefetched by the server according to
+// fetch plans, as well as records loaded or saved by the client. Records with older versions never replace newer ones.
+//
+// Cache is not aware of changes made by SQL commands or other clients, so it's used only when caller allows it
+// (see Database.GetRecordByRID), and is disabled by default (see Options.RecordCacheSize).
+// All methods are safe for concurrent use, nil cache ignores all records.
+type RecordCache struct {
+	mu    sync.Mutex
+	size  int
+	lru   *list.List // front is the most recently used
+	items map[RID]*list.Element
+}
+
+// NewRecordCache creates a new cache that holds up to size records. Zero size means no limit.
+func NewRecordCache(size int) *RecordCache {
+	return &RecordCache{size: size, lru: list.New(), items: make(map[RID]*list.Element)}
+}
+
+// newRecordCache creates a per-database cache. Cache is disabled unless its size is set in options.
+func newRecordCache(opts Options) *RecordCache {
+	if opts.RecordCacheSize <= 0 {
+		return nil
+	}
+	return NewRecordCache(opts.RecordCacheSize)
+}
+
+// Len returns a number of cached records.
+func (c *RecordCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Get returns a copy of cached record, or nil if it's not in cache.
+func (c *RecordCache) Get(rid RID) ORecord {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	el, ok := c.items[rid]
+	if !ok {
+		c.mu.Unlock()
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	cr := *el.Value.(*cachedRecord)
+	c.mu.Unlock()
+
+	fnc := GetRecordFactory(cr.tp)
+	if fnc == nil {
+		return nil
+	}
+	rec := fnc()
+	if doc, ok := rec.(*Document); ok && cr.ser != nil {
+		doc.SetSerializer(cr.ser)
+	}
+	if err := rec.Fill(cr.rid, cr.vers, cr.data); err != nil {
+		return nil
+	}
+	return rec
+}
+
+// Put stores a copy of the record in cache. Records that are not persistent, or that are older than the cached ones are ignored.
+func (c *RecordCache) Put(rec ORecord) {
+	if c == nil || rec == nil {
+		return
+	}
+	rid := rec.GetIdentity()
+	if !rid.IsPersistent() {
+		return
+	}
+	data, err := rec.Content()
+	if err != nil {
+		c.Remove(rid)
+		return
+	}
+	cr := &cachedRecord{
+		rid:  rid,
+		vers: rec.Version(),
+		tp:   rec.RecordType(),
+		data: append([]byte(nil), data...),
+	}
+	if doc, ok := rec.(*Document); ok {
+		cr.ser = doc.ser
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[rid]; ok {
+		if el.Value.(*cachedRecord).vers > cr.vers {
+			return
+		}
+		el.Value = cr
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[rid] = c.lru.PushFront(cr)
+	for c.size > 0 && c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.items, el.Value.(*cachedRecord).rid)
+	}
+}
+
+// Remove deletes the record from cache.
+func (c *RecordCache) Remove(rid RID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	if el, ok := c.items[rid]; ok {
+		c.lru.Remove(el)
+		delete(c.items, rid)
+	}
+	c.mu.Unlock()
+}
+
+// Clear removes all records from cache.
+func (c *RecordCache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.lru.Init()
+	c.items = make(map[RID]*list.Element)
+	c.mu.Unlock()
+}
+
+// ResolveLinks replaces links in document fields with cached documents. Links of resolved documents are
+// resolved as well, so the whole graph prefetched by the server becomes available without additional requests.
+// Links to records that are not cached are left untouched.
+func (c *RecordCache) ResolveLinks(doc *Document) {
+	if c == nil || doc == nil {
+		return
+	}
+	seen := map[RID]*Document{doc.GetIdentity(): doc}
+	c.resolveLinks(doc, seen)
+}
+
+func (c *RecordCache) resolveLinks(doc *Document, seen map[RID]*Document) {
+	for _, fld := range doc.Fields() {
+		switch fld.Type {
+		case LINK:
+			if id, ok := fld.Value.(OIdentifiable); ok {
+				if ldoc := c.resolve(id, seen); ldoc != nil {
+					fld.Value = ldoc
+				}
+			}
+		case LINKLIST, LINKSET:
+			if ids, ok := fld.Value.([]OIdentifiable); ok {
+				for i, id := range ids {
+					if ldoc := c.resolve(id, seen); ldoc != nil {
+						ids[i] = ldoc
+					}
+				}
+			}
+		}
+	}
+}
+
+// resolve returns a cached document for a given link, or nil if link is already resolved or record is not cached.
+func (c *RecordCache) resolve(id OIdentifiable, seen map[RID]*Document) *Document {
+	if id == nil || id.GetRecord() != nil {
+		return nil
+	}
+	rid := id.GetIdentity()
+	if doc, ok := seen[rid]; ok {
+		return doc
+	}
+	doc, ok := c.Get(rid).(*Document)
+	if !ok {
+		return nil
+	}
+	seen[rid] = doc
+	c.resolveLinks(doc, seen)
+	return doc
+}
@@ -0,0 +1,88 @@
+package orient
+
+import (
+	"testing"
+)
+
+func newCachedDoc(t *testing.T, rid RID, vers int, fields map[string]interface{}) *Document {
+	doc := NewDocument("V")
+	for name, val := range fields {
+		doc.SetField(name, val)
+	}
+	data, err := doc.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Fill(rid, vers, data)
+	return doc
+}
+
+func TestRecordCacheLRU(t *testing.T) {
+	c := NewRecordCache(2)
+	for i := 1; i <= 3; i++ {
+		if i == 3 {
+			c.Get(NewRID(1, 1)) // make the first record recently used
+		}
+		c.Put(newCachedDoc(t, NewRID(1, int64(i)), 1, map[string]interface{}{"n": int32(i)}))
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", c.Len())
+	} else if c.Get(NewRID(1, 2)) != nil {
+		t.Fatal("least recently used record was not evicted")
+	} else if c.Get(NewRID(1, 1)) == nil || c.Get(NewRID(1, 3)) == nil {
+		t.Fatal("recently used records were evicted")
+	}
+	c.Remove(NewRID(1, 1))
+	if c.Get(NewRID(1, 1)) != nil {
+		t.Fatal("record was not removed")
+	}
+	c.Put(NewDocument("V")) // not persistent
+	if c.Len() != 1 {
+		t.Fatalf("expected 1 record, got %d", c.Len())
+	}
+	c.Clear()
+	if c.Len() != 0 {
+		t.Fatal("cache was not cleared")
+	}
+}
+
+func TestRecordCacheVersion(t *testing.T) {
+	c := NewRecordCache(10)
+	rid := NewRID(1, 1)
+	c.Put(newCachedDoc(t, rid, 2, map[string]interface{}{"name": "new"}))
+	c.Put(newCachedDoc(t, rid, 1, map[string]interface{}{"name": "old"}))
+	doc, ok := c.Get(rid).(*Document)
+	if !ok {
+		t.Fatal("record is not cached")
+	} else if doc.Version() != 2 || doc.GetField("name").Value != "new" {
+		t.Fatalf("older record replaced newer one: %v", doc)
+	}
+	doc.SetField("name", "changed")
+	if doc2 := c.Get(rid).(*Document); doc2 == doc || doc2.GetField("name").Value != "new" {
+		t.Fatal("cache returned shared record")
+	}
+}
+
+func TestRecordCacheResolveLinks(t *testing.T) {
+	c := NewRecordCache(10)
+	r1, r2, r3 := NewRID(1, 1), NewRID(1, 2), NewRID(1, 3)
+	c.Put(newCachedDoc(t, r2, 1, map[string]interface{}{"name": "b", "back": r1}))
+
+	doc := newCachedDoc(t, r1, 1, map[string]interface{}{
+		"one":  r2,
+		"many": []OIdentifiable{r2, r3},
+	})
+	c.ResolveLinks(doc)
+	one, ok := doc.GetField("one").Value.(*Document)
+	if !ok || one.GetField("name").Value != "b" {
+		t.Fatalf("link was not resolved: %v", doc.GetField("one"))
+	} else if back, ok := one.GetField("back").Value.(*Document); !ok || back != doc {
+		t.Fatalf("cyclic link was not resolved to the same document: %v", one.GetField("back"))
+	}
+	many := doc.GetField("many").Value.([]OIdentifiable)
+	if many[0] != one {
+		t.Fatal("the same record resolved to different documents")
+	} else if many[1] != r3 {
+		t.Fatal("link to not cached record was changed")
+	}
+}
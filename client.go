@@ -1,9 +1,12 @@
 package orient // import "gopkg.in/istreamdata/orientgo.v2"
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,6 +23,8 @@ var (
 // n < 0 - no limit for retries
 //
 // n > 0 - maximum of n retries
+//
+// The setting can be overridden per client with Options.RetryCount.
 func SetRetryCountConcurrent(n int) {
 	if n == 0 {
 		n = concurrentRetriesDefault
@@ -30,6 +35,7 @@ func SetRetryCountConcurrent(n int) {
 }
 
 // MaxConnections limits the number of opened connections.
+// The setting can be overridden per client with Options.MaxConnections.
 var MaxConnections = 6
 
 // FetchPlan is an additional parameter to queries, that instructs DB how to handle linked documents.
@@ -42,7 +48,8 @@ var MaxConnections = 6
 //
 // Depth is the depth level to fetch. -1 means infinite, 0 means no fetch at all and 1-N the depth level value.
 //
-// WARN: currently fetch plan have no effect on returned results, as records cache is not implemented yet.
+// Records prefetched by the server are stored in the database records cache (see RecordCache),
+// and links in returned documents are resolved to these records.
 type FetchPlan string
 
 const (
@@ -60,119 +67,138 @@ const (
 //
 //		import _  "gopkg.in/istreamdata/orientgo.v2/obinary"
 //
-// Address must be in host:port format. Connection to OrientDB cluster is not supported yet.
+// Address must be in host:port format. To connect to distributed OrientDB cluster, pass a list of nodes
+// separated by semicolons, or an URL in a form of "remote:host1;host2;host3" (see ParseAddrs).
+// Connections will be made to the first healthy node and will fail over to other nodes if it goes down.
 //
 // Returned Client uses connection pool under the hood, so it can be shared between goroutines.
 func Dial(addr string) (*Client, error) {
-	dial := protos[ProtoBinary]
-	if dial == nil {
-		return nil, fmt.Errorf("orientgo: no protocols are active; forgot to import obinary package?")
-	}
-	cli := &Client{
-		dial: func() (DBConnection, error) {
-			return dial(addr)
-		},
-	}
-	conn, err := cli.dial()
-	if err != nil {
-		return nil, err
-	}
-	cli.mconn = conn
-	return cli, nil
+	return DialWithOptions(addr, Options{})
 }
 
-func newConnPool(size int, dial func() (DBSession, error)) *connPool {
-	if size == 0 {
-		size = MaxConnections
-	}
-	p := &connPool{
-		dial: dial,
-	}
-	if size > 0 {
-		p.ch = make(chan DBSession, size)
-		p.toks = make(chan struct{}, size)
-		for i := 0; i < size; i++ {
-			p.toks <- struct{}{}
-		}
-	} else {
-		p.ch = make(chan DBSession, 10)
-	}
-	return p
-}
+// Options is a set of client settings for DialWithOptions. Zero values mean defaults.
+type Options struct {
+	// Strategy defines how connections are distributed between cluster nodes. Default is StrategySticky.
+	Strategy DialStrategy
+	// TLSConfig enables TLS (SSL) transport for connections if set. It can be used to set client
+	// certificates and custom root CAs. If ServerName is not set, it is taken from node address.
+	TLSConfig *tls.Config
 
-type connPool struct {
-	dial func() (DBSession, error)
-	ch   chan DBSession
-	toks chan struct{}
+	// MaxConnections limits the number of opened connections per database. Negative value means no limit.
+	// Default is taken from global MaxConnections variable.
+	MaxConnections int
+	// RetryCount sets a retry count when ErrConcurrentModification occurs. Negative value means no limit.
+	// Default is taken from global setting (see SetRetryCountConcurrent).
+	RetryCount int
+
+	// DialTimeout limits the time for establishing TCP (and TLS) connection. Default is 1 minute.
+	DialTimeout time.Duration
+	// HandshakeTimeout limits the time for protocol version negotiation. Default is 5 seconds.
+	HandshakeTimeout time.Duration
+	// ReadTimeout limits the time to wait for a response for each request. Default is no timeout.
+	ReadTimeout time.Duration
+
+	// MaxIdleTime is a maximal amount of time a session may be idle before being closed. Default is no limit.
+	MaxIdleTime time.Duration
+	// MaxLifetime is a maximal amount of time a session may be reused. Default is no limit.
+	MaxLifetime time.Duration
+	// PingOnBorrow enables sending a light-weight request to the server to check session state
+	// each time it is taken from the pool. Broken sessions are replaced with new ones.
+	PingOnBorrow bool
+
+	// UseToken enables token-based database sessions. Credentials passed to Open are used only
+	// to obtain the token, and new sessions (for example, after a disconnect) are opened with the token.
+	// If the token expires, the database must be opened again.
+	UseToken bool
+
+	// RecordCacheSize enables per-database records cache that holds up to a given number of records.
+	// Cache is disabled by default, since it's not aware of changes made by SQL commands or other clients.
+	RecordCacheSize int
+
+	// RecordFormat is a name of record serializer registered with RegisterRecordFormat.
+	// Default is set by SetDefaultRecordFormat.
+	RecordFormat string
 }
 
-func (p *connPool) getConn() (DBSession, error) {
-	var dt <-chan time.Time
-	if p.toks == nil {
-		dt = time.After(time.Millisecond * 100)
-	}
-	select {
-	case conn := <-p.ch:
-		return conn, nil
-	case <-p.toks:
-	case <-dt:
-	}
-	if p.dial == nil {
-		return nil, nil
+// DialWithOptions is like Dial, but allows to set additional client options.
+func DialWithOptions(addr string, opts Options) (*Client, error) {
+	dial := protos[ProtoBinary]
+	if dial == nil {
+		return nil, fmt.Errorf("orientgo: no protocols are active; forgot to import obinary package?")
 	}
-	conn, err := p.dial()
+	addrs, err := ParseAddrs(addr)
 	if err != nil {
 		return nil, err
 	}
-	return conn, nil
-}
-func (p *connPool) putConn(conn DBSession) {
-	select {
-	case p.ch <- conn:
-	default:
-		if p.toks != nil {
-			select {
-			case p.toks <- struct{}{}:
-			default:
-			}
+	if opts.RecordFormat != "" {
+		if _, ok := recordFormats[opts.RecordFormat]; !ok {
+			return nil, fmt.Errorf("orientgo: unknown record format: %s", opts.RecordFormat)
 		}
-		conn.Close()
 	}
-}
-func (p *connPool) clear() {
-loop:
-	for {
-		select {
-		case conn := <-p.ch:
-			if conn != nil {
-				conn.Close()
-			}
-		case <-p.toks:
-		default:
-			break loop
-		}
+	cli := &Client{
+		opts:  opts,
+		nodes: newNodeList(addrs, opts.Strategy),
+		proto: dial,
 	}
-	for len(p.toks) < cap(p.toks) {
-		p.toks <- struct{}{}
+	conn, err := cli.dial(false)
+	if err != nil {
+		return nil, err
 	}
+	cli.mconn = conn
+	return cli, nil
 }
 
 // Client represents connection to OrientDB server. It is safe for concurrent use.
 type Client struct {
+	opts  Options
 	mconn DBConnection
-	dial  func() (DBConnection, error)
+	nodes *nodeList
+	proto func(addr string, opts Options) (DBConnection, error)
+}
+
+// retryCount returns a retry count for concurrent modification errors.
+func (c *Client) retryCount() int {
+	if c.opts.RetryCount < 0 {
+		return -1
+	} else if c.opts.RetryCount > 0 {
+		return c.opts.RetryCount
+	}
+	return concurrentRetries
+}
+
+// dial opens a new connection to one of the cluster nodes. Read flag indicates that connection
+// will be used only for queries and record reads.
+func (c *Client) dial(read bool) (DBConnection, error) {
+	return c.nodes.dial(read, func(addr string) (DBConnection, error) {
+		return c.proto(addr, c.opts)
+	})
 }
 
 // Auth initiates a new administration session with OrientDB server, allowing to manage databases.
 func (c *Client) Auth(user, pass string) (*Admin, error) {
+	return c.AuthContext(context.Background(), user, pass)
+}
+
+// AuthContext is like Auth, but allows to cancel the request with a context.
+func (c *Client) AuthContext(ctx context.Context, user, pass string) (*Admin, error) {
 	if c.mconn == nil {
-		conn, err := c.dial()
+		conn, err := c.dial(false)
 		if err != nil {
 			return nil, err
 		}
 		c.mconn = conn
 	}
-	m, err := c.mconn.Auth(user, pass)
+	m, err := c.mconn.AuthContext(ctx, user, pass)
+	if isConnError(err) { // node is down - try another one
+		c.mconn.Close()
+		c.mconn = nil
+		conn, derr := c.dial(false)
+		if derr != nil {
+			return nil, err
+		}
+		c.mconn = conn
+		m, err = c.mconn.AuthContext(ctx, user, pass)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -184,6 +210,14 @@ type sessionAndConn struct {
 	conn DBConnection
 }
 
+// Alive checks if connection is still usable, without sending requests to the server.
+func (s sessionAndConn) Alive() bool {
+	if ac, ok := s.DBSession.(aliveChecker); ok {
+		return ac.Alive()
+	}
+	return true
+}
+
 func (s sessionAndConn) Close() error {
 	err := s.DBSession.Close()
 	if err1 := s.conn.Close(); err == nil {
@@ -196,19 +230,39 @@ func (s sessionAndConn) Close() error {
 //
 // For database management use Auth instead.
 func (c *Client) Open(name string, dbType DatabaseType, user, pass string) (*Database, error) {
-	db := &Database{pool: newConnPool(0, func() (DBSession, error) {
-		conn, err := c.dial()
-		if err != nil {
-			return nil, err
-		}
-		ds, err := conn.Open(name, dbType, user, pass)
-		if err != nil {
-			conn.Close()
-			return nil, err
+	return c.OpenContext(context.Background(), name, dbType, user, pass)
+}
+
+// OpenContext is like Open, but allows to cancel the request with a context.
+//
+// If client uses StrategyReadAnyWriteMaster, database will use a separate pool of sessions for reads.
+//
+// If Options.UseToken is set, credentials are kept only until the first session token is received.
+func (c *Client) OpenContext(ctx context.Context, name string, dbType DatabaseType, user, pass string) (*Database, error) {
+	db := &Database{cli: c, cache: newRecordCache(c.opts)}
+	db.auth.user, db.auth.pass = user, pass
+	open := func(read bool) func(ctx context.Context) (DBSession, error) {
+		return func(ctx context.Context) (DBSession, error) {
+			conn, err := c.dial(read)
+			if err != nil {
+				return nil, err
+			}
+			ds, err := db.auth.open(ctx, conn, name, dbType)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			if cu, ok := ds.(recordCacheUser); ok && db.cache != nil {
+				cu.SetRecordCache(db.cache)
+			}
+			return sessionAndConn{DBSession: ds, conn: conn}, nil
 		}
-		return sessionAndConn{DBSession: ds, conn: conn}, nil
-	}), cli: c}
-	conn, err := db.pool.getConn()
+	}
+	db.pool = newConnPool(c.opts, open(false))
+	if c.opts.Strategy == StrategyReadAnyWriteMaster && len(c.nodes.nodes) > 1 {
+		db.rpool = newConnPool(c.opts, open(true))
+	}
+	conn, err := db.pool.getConnContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -232,17 +286,32 @@ type Admin struct {
 
 // DatabaseExists checks if database with given name and storage type exists.
 func (a *Admin) DatabaseExists(name string, storageType StorageType) (bool, error) {
-	return a.db.DatabaseExists(name, storageType)
+	return a.DatabaseExistsContext(context.Background(), name, storageType)
+}
+
+// DatabaseExistsContext is like DatabaseExists, but allows to cancel the request with a context.
+func (a *Admin) DatabaseExistsContext(ctx context.Context, name string, storageType StorageType) (bool, error) {
+	return a.db.DatabaseExistsContext(ctx, name, storageType)
 }
 
 // CreateDatabase creates a new database with given database type (Document or Graph) and storage type (Persistent or Volatile).
 func (a *Admin) CreateDatabase(name string, dbType DatabaseType, storageType StorageType) error {
-	return a.db.CreateDatabase(name, dbType, storageType)
+	return a.CreateDatabaseContext(context.Background(), name, dbType, storageType)
+}
+
+// CreateDatabaseContext is like CreateDatabase, but allows to cancel the request with a context.
+func (a *Admin) CreateDatabaseContext(ctx context.Context, name string, dbType DatabaseType, storageType StorageType) error {
+	return a.db.CreateDatabaseContext(ctx, name, dbType, storageType)
 }
 
 // DropDatabase removes database from the server.
 func (a *Admin) DropDatabase(name string, storageType StorageType) error {
-	return a.db.DropDatabase(name, storageType)
+	return a.DropDatabaseContext(context.Background(), name, storageType)
+}
+
+// DropDatabaseContext is like DropDatabase, but allows to cancel the request with a context.
+func (a *Admin) DropDatabaseContext(ctx context.Context, name string, storageType StorageType) error {
+	return a.db.DropDatabaseContext(ctx, name, storageType)
 }
 
 // ListDatabases returns a list of databases in a form:
@@ -250,7 +319,93 @@ func (a *Admin) DropDatabase(name string, storageType StorageType) error {
 // 		dbname: dbpath
 //
 func (a *Admin) ListDatabases() (map[string]string, error) {
-	return a.db.ListDatabases()
+	return a.ListDatabasesContext(context.Background())
+}
+
+// ListDatabasesContext is like ListDatabases, but allows to cancel the request with a context.
+func (a *Admin) ListDatabasesContext(ctx context.Context) (map[string]string, error) {
+	return a.db.ListDatabasesContext(ctx)
+}
+
+// ServerConfig returns all server configuration values.
+func (a *Admin) ServerConfig() (map[string]string, error) {
+	return a.ServerConfigContext(context.Background())
+}
+
+// ServerConfigContext is like ServerConfig, but allows to cancel the request with a context.
+func (a *Admin) ServerConfigContext(ctx context.Context) (map[string]string, error) {
+	return a.db.ServerConfigContext(ctx)
+}
+
+// ServerConfigValue returns a single server configuration value.
+func (a *Admin) ServerConfigValue(key string) (string, error) {
+	return a.ServerConfigValueContext(context.Background(), key)
+}
+
+// ServerConfigValueContext is like ServerConfigValue, but allows to cancel the request with a context.
+func (a *Admin) ServerConfigValueContext(ctx context.Context, key string) (string, error) {
+	return a.db.ServerConfigValueContext(ctx, key)
+}
+
+// SetServerConfig changes a server configuration value. Changes are not persisted across server restarts.
+func (a *Admin) SetServerConfig(key, val string) error {
+	return a.SetServerConfigContext(context.Background(), key, val)
+}
+
+// SetServerConfigContext is like SetServerConfig, but allows to cancel the request with a context.
+func (a *Admin) SetServerConfigContext(ctx context.Context, key, val string) error {
+	return a.db.SetServerConfigContext(ctx, key, val)
+}
+
+// Shutdown stops the server. User must have "server.shutdown" permission.
+func (a *Admin) Shutdown() error {
+	return a.ShutdownContext(context.Background())
+}
+
+// ShutdownContext is like Shutdown, but allows to cancel the request with a context.
+func (a *Admin) ShutdownContext(ctx context.Context) error {
+	return a.db.ShutdownContext(ctx)
+}
+
+// FreezeDatabase flushes database to disk and blocks all write operations until ReleaseDatabase is called.
+// It allows to make a consistent snapshot of database files without stopping the server.
+func (a *Admin) FreezeDatabase(name string, storageType StorageType) error {
+	return a.FreezeDatabaseContext(context.Background(), name, storageType)
+}
+
+// FreezeDatabaseContext is like FreezeDatabase, but allows to cancel the request with a context.
+func (a *Admin) FreezeDatabaseContext(ctx context.Context, name string, storageType StorageType) error {
+	return a.db.FreezeDatabaseContext(ctx, name, storageType)
+}
+
+// ReleaseDatabase allows write operations on a database frozen by FreezeDatabase.
+func (a *Admin) ReleaseDatabase(name string, storageType StorageType) error {
+	return a.ReleaseDatabaseContext(context.Background(), name, storageType)
+}
+
+// ReleaseDatabaseContext is like ReleaseDatabase, but allows to cancel the request with a context.
+func (a *Admin) ReleaseDatabaseContext(ctx context.Context, name string, storageType StorageType) error {
+	return a.db.ReleaseDatabaseContext(ctx, name, storageType)
+}
+
+// FreezeCluster blocks write operations on a single cluster of the database until ReleaseCluster is called.
+func (a *Admin) FreezeCluster(name string, clusterID int16, storageType StorageType) error {
+	return a.FreezeClusterContext(context.Background(), name, clusterID, storageType)
+}
+
+// FreezeClusterContext is like FreezeCluster, but allows to cancel the request with a context.
+func (a *Admin) FreezeClusterContext(ctx context.Context, name string, clusterID int16, storageType StorageType) error {
+	return a.db.FreezeClusterContext(ctx, name, clusterID, storageType)
+}
+
+// ReleaseCluster allows write operations on a cluster frozen by FreezeCluster.
+func (a *Admin) ReleaseCluster(name string, clusterID int16, storageType StorageType) error {
+	return a.ReleaseClusterContext(context.Background(), name, clusterID, storageType)
+}
+
+// ReleaseClusterContext is like ReleaseCluster, but allows to cancel the request with a context.
+func (a *Admin) ReleaseClusterContext(ctx context.Context, name string, clusterID int16, storageType StorageType) error {
+	return a.db.ReleaseClusterContext(ctx, name, clusterID, storageType)
 }
 
 // Close closes DB management session.
@@ -260,46 +415,138 @@ func (a *Admin) Close() error {
 
 // Database wraps a database session. It is safe for concurrent use.
 type Database struct {
-	pool *connPool
-	cli  *Client
+	pool  *connPool // sessions for writes; also used for reads if rpool is not set
+	rpool *connPool // sessions for reads, used by StrategyReadAnyWriteMaster
+	cli   *Client
+	auth  dbAuth
+	cache *RecordCache // nil if disabled
+
+	schemaGen   int64 // incremented on each schema change; accessed atomically
+	schemaMu    sync.Mutex
+	schemaHooks []func(schema *ODatabase)
 }
 
-// Size return the size of current database (in bytes).
-func (db *Database) Size() (int64, error) {
-	conn, err := db.pool.getConn()
+// withConn takes a session from the pool, runs fnc with it and returns session back to the pool.
+// Read flag indicates that fnc only reads the data, so the session may be taken from the read pool.
+// Sessions that failed with connection error are closed, so next requests will dial a healthy node.
+func (db *Database) withConn(ctx context.Context, read bool, fnc func(conn DBSession) error) error {
+	conn, release, err := db.getConn(ctx, read)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	defer db.pool.putConn(conn)
-	return conn.Size()
+	err = fnc(conn)
+	release(err)
+	return err
+}
+
+// getConn takes a session from the pool. Returned function must be called with the last error
+// of the session to return it back to the pool.
+func (db *Database) getConn(ctx context.Context, read bool) (DBSession, func(err error), error) {
+	p := db.pool
+	if read && db.rpool != nil {
+		p = db.rpool
+	}
+	conn, err := p.getConnContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = db.syncSchema(ctx, conn); err != nil {
+		if isConnError(err) {
+			p.discard(conn)
+		} else {
+			p.putConn(conn)
+		}
+		return nil, nil, err
+	}
+	return conn, func(err error) {
+		db.auth.update(conn.Token())
+		if isConnError(err) {
+			p.discard(conn)
+		} else {
+			p.putConn(conn)
+		}
+	}, nil
+}
+
+// Cache returns records cache of the database, or nil if cache is disabled.
+func (db *Database) Cache() *RecordCache {
+	return db.cache
+}
+
+// Stats returns connection pool statistics. If client uses StrategyReadAnyWriteMaster,
+// statistics for both read and write pools are combined.
+func (db *Database) Stats() PoolStats {
+	st := db.pool.stats()
+	if db.rpool != nil {
+		st.add(db.rpool.stats())
+	}
+	return st
+}
+
+// Ping checks if database is reachable by sending a light-weight request to the server.
+func (db *Database) Ping() error {
+	return db.PingContext(context.Background())
+}
+
+// PingContext is like Ping, but allows to cancel the request with a context.
+func (db *Database) PingContext(ctx context.Context) error {
+	return db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.PingContext(ctx)
+	})
 }
 
-// Close closes database session.
+// Size return the size of current database (in bytes).
+func (db *Database) Size() (int64, error) {
+	return db.SizeContext(context.Background())
+}
+
+// SizeContext is like Size, but allows to cancel the request with a context.
+func (db *Database) SizeContext(ctx context.Context) (int64, error) {
+	var size int64
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		size, err = conn.SizeContext(ctx)
+		return
+	})
+	return size, err
+}
+
+// Close closes database sessions. Sessions that are still in use are closed when released,
+// and further requests fail with ErrClosedDatabase.
 func (db *Database) Close() error {
-	if db != nil && db.pool != nil {
-		db.pool.clear()
+	if db == nil {
+		return nil
+	}
+	if db.pool != nil {
+		db.pool.close()
+	}
+	if db.rpool != nil {
+		db.rpool.close()
 	}
 	return nil
 }
 
 // ReloadSchema reloads documents schema from database.
 func (db *Database) ReloadSchema() error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
-	}
-	defer db.pool.putConn(conn)
-	return conn.ReloadSchema()
+	return db.ReloadSchemaContext(context.Background())
+}
+
+// ReloadSchemaContext is like ReloadSchema, but allows to cancel the request with a context.
+//
+// Schema is reloaded by all pooled sessions of the database before their next use.
+func (db *Database) ReloadSchemaContext(ctx context.Context) error {
+	return db.withConn(ctx, false, func(conn DBSession) error {
+		return db.schemaChanged(ctx, conn)
+	})
 }
 
 // GetCurDB returns database metadata
 func (db *Database) GetCurDB() *ODatabase {
-	conn, err := db.pool.getConn()
-	if err != nil {
+	var cur *ODatabase
+	db.withConn(context.Background(), false, func(conn DBSession) error {
+		cur = conn.GetCurDB()
 		return nil
-	}
-	defer db.pool.putConn(conn)
-	return conn.GetCurDB()
+	})
+	return cur
 }
 
 // AddCluster creates new cluster with given name and returns its ID.
@@ -309,93 +556,320 @@ func (db *Database) AddCluster(name string) (int16, error) {
 
 // AddClusterWithID creates new cluster with given cluster position and name
 func (db *Database) AddClusterWithID(name string, clusterID int16) (int16, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.AddClusterWithID(name, clusterID)
+	return db.AddClusterWithIDContext(context.Background(), name, clusterID)
+}
+
+// AddClusterWithIDContext is like AddClusterWithID, but allows to cancel the request with a context.
+func (db *Database) AddClusterWithIDContext(ctx context.Context, name string, clusterID int16) (int16, error) {
+	var id int16
+	err := db.withConn(ctx, false, func(conn DBSession) (err error) {
+		id, err = conn.AddClusterWithIDContext(ctx, name, clusterID)
+		return
+	})
+	return id, err
 }
 
 // DropCluster deletes cluster from database
 func (db *Database) DropCluster(name string) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
-	}
-	defer db.pool.putConn(conn)
-	return conn.DropCluster(name)
+	return db.DropClusterContext(context.Background(), name)
+}
+
+// DropClusterContext is like DropCluster, but allows to cancel the request with a context.
+func (db *Database) DropClusterContext(ctx context.Context, name string) error {
+	return db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.DropClusterContext(ctx, name)
+	})
 }
 
 // GetClusterDataRange returns the begin and end positions of data in the requested cluster.
 func (db *Database) GetClusterDataRange(clusterName string) (begin, end int64, err error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.GetClusterDataRange(clusterName)
+	return db.GetClusterDataRangeContext(context.Background(), clusterName)
+}
+
+// GetClusterDataRangeContext is like GetClusterDataRange, but allows to cancel the request with a context.
+func (db *Database) GetClusterDataRangeContext(ctx context.Context, clusterName string) (begin, end int64, err error) {
+	err = db.withConn(ctx, true, func(conn DBSession) (err error) {
+		begin, end, err = conn.GetClusterDataRangeContext(ctx, clusterName)
+		return
+	})
+	return
 }
 
 // ClustersCount returns total count of records in given clusters
 func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (int64, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.ClustersCount(withDeleted, clusterNames...)
+	return db.ClustersCountContext(context.Background(), withDeleted, clusterNames...)
+}
+
+// ClustersCountContext is like ClustersCount, but allows to cancel the request with a context.
+func (db *Database) ClustersCountContext(ctx context.Context, withDeleted bool, clusterNames ...string) (int64, error) {
+	var n int64
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		n, err = conn.ClustersCountContext(ctx, withDeleted, clusterNames...)
+		return
+	})
+	return n, err
 }
 
 // CreateRecord saves a record to the database. Record RID and version will be changed.
 func (db *Database) CreateRecord(rec ORecord) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
+	return db.CreateRecordContext(context.Background(), rec)
+}
+
+// CreateRecordContext is like CreateRecord, but allows to cancel the request with a context.
+func (db *Database) CreateRecordContext(ctx context.Context, rec ORecord) error {
+	err := db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.CreateRecordContext(ctx, rec)
+	})
+	if doc, ok := rec.(*Document); ok && err == nil {
+		doc.updateSource()
 	}
-	defer db.pool.putConn(conn)
-	return conn.CreateRecord(rec)
+	return err
 }
 
 // DeleteRecordByRID removes a record from database
 func (db *Database) DeleteRecordByRID(rid RID, recVersion int) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
+	return db.DeleteRecordByRIDContext(context.Background(), rid, recVersion)
+}
+
+// DeleteRecordByRIDContext is like DeleteRecordByRID, but allows to cancel the request with a context.
+func (db *Database) DeleteRecordByRIDContext(ctx context.Context, rid RID, recVersion int) error {
+	return db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.DeleteRecordByRIDContext(ctx, rid, recVersion)
+	})
+}
+
+// RecordMetadata returns current version of a record without loading its content.
+// It can be used to check if a record was modified before sending an update.
+func (db *Database) RecordMetadata(rid RID) (version int, err error) {
+	return db.RecordMetadataContext(context.Background(), rid)
+}
+
+// RecordMetadataContext is like RecordMetadata, but allows to cancel the request with a context.
+func (db *Database) RecordMetadataContext(ctx context.Context, rid RID) (version int, err error) {
+	err = db.withConn(ctx, true, func(conn DBSession) error {
+		version, err = conn.RecordMetadataContext(ctx, rid)
 		return err
-	}
-	defer db.pool.putConn(conn)
-	return conn.DeleteRecordByRID(rid, recVersion)
+	})
+	return
+}
+
+// HideRecord marks a record as deleted without touching its content. It's intended for repairing
+// corrupted records that cannot be loaded or deleted in a usual way.
+func (db *Database) HideRecord(rid RID) error {
+	return db.HideRecordContext(context.Background(), rid)
+}
+
+// HideRecordContext is like HideRecord, but allows to cancel the request with a context.
+func (db *Database) HideRecordContext(ctx context.Context, rid RID) error {
+	return db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.HideRecordContext(ctx, rid)
+	})
+}
+
+// CleanOutRecord removes a record and frees its cluster position, so it can be reused by new records.
+// Like HideRecord, it's intended for repairing corrupted records.
+func (db *Database) CleanOutRecord(rid RID, recVersion int) error {
+	return db.CleanOutRecordContext(context.Background(), rid, recVersion)
+}
+
+// CleanOutRecordContext is like CleanOutRecord, but allows to cancel the request with a context.
+func (db *Database) CleanOutRecordContext(ctx context.Context, rid RID, recVersion int) error {
+	return db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.CleanOutRecordContext(ctx, rid, recVersion)
+	})
 }
 
 // GetRecordByRID returns a record using specified fetch plan. If ignoreCache is set to true implementations will
 // not use local records cache and will fetch record from database.
 func (db *Database) GetRecordByRID(rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, error) {
-	conn, err := db.pool.getConn()
+	return db.GetRecordByRIDContext(context.Background(), rid, fetchPlan, ignoreCache)
+}
+
+// GetRecordByRIDContext is like GetRecordByRID, but allows to cancel the request with a context.
+func (db *Database) GetRecordByRIDContext(ctx context.Context, rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, error) {
+	if !ignoreCache {
+		if rec := db.cache.Get(rid); rec != nil {
+			db.bind(rec)
+			return rec, nil
+		}
+	}
+	var rec ORecord
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		rec, err = conn.GetRecordByRIDContext(ctx, rid, fetchPlan, ignoreCache)
+		return
+	})
+	db.bind(rec)
+	return rec, err
+}
+
+// loadRecordsChunk is a maximal number of records requested by LoadRecords at once.
+const loadRecordsChunk = 100
+
+// LoadRecords loads records with given RIDs using specified fetch plan. Records are returned in the same order as RIDs.
+// Records cache is used for records that were loaded before, other records are requested in batches.
+//
+// If some records do not exist, nil is returned in their positions, together with ErrMissingRecords error.
+func (db *Database) LoadRecords(rids []RID, plan FetchPlan) ([]ORecord, error) {
+	return db.LoadRecordsContext(context.Background(), rids, plan)
+}
+
+// LoadRecordsContext is like LoadRecords, but allows to cancel the request with a context.
+func (db *Database) LoadRecordsContext(ctx context.Context, rids []RID, plan FetchPlan) ([]ORecord, error) {
+	recs, err := db.loadRecords(ctx, rids, plan, false)
 	if err != nil {
 		return nil, err
 	}
-	defer db.pool.putConn(conn)
-	return conn.GetRecordByRID(rid, fetchPlan, ignoreCache)
+	out := make([]ORecord, len(rids))
+	var missing []RID
+	for i, rid := range rids {
+		if rec, ok := recs[rid]; ok {
+			out[i] = rec
+		} else {
+			missing = append(missing, rid)
+		}
+	}
+	if len(missing) != 0 {
+		return out, ErrMissingRecords{RIDs: missing}
+	}
+	return out, nil
+}
+
+// loadRecords loads records with given RIDs from cache or from database. Records that do not exist are not returned.
+func (db *Database) loadRecords(ctx context.Context, rids []RID, plan FetchPlan, ignoreCache bool) (map[RID]ORecord, error) {
+	out := make(map[RID]ORecord, len(rids))
+	var missing []RID
+	for _, rid := range rids {
+		if _, ok := out[rid]; ok {
+			continue
+		} else if rec := db.cache.Get(rid); rec != nil && !ignoreCache {
+			db.bind(rec)
+			out[rid] = rec
+			continue
+		}
+		out[rid] = nil
+		missing = append(missing, rid)
+	}
+	for _, rid := range missing {
+		delete(out, rid)
+	}
+	if len(missing) == 1 {
+		rec, err := db.GetRecordByRIDContext(ctx, missing[0], plan, true)
+		if err != nil {
+			return nil, err
+		} else if rec != nil {
+			out[missing[0]] = rec
+		}
+		return out, nil
+	}
+	for len(missing) > 0 {
+		chunk := missing
+		if len(chunk) > loadRecordsChunk {
+			chunk = chunk[:loadRecordsChunk]
+		}
+		missing = missing[len(chunk):]
+
+		strs := make([]string, len(chunk))
+		for i, rid := range chunk {
+			strs[i] = rid.String()
+		}
+		var recs []OIdentifiable
+		q := NewSQLQuery("SELECT FROM [" + strings.Join(strs, ", ") + "]").FetchPlan(plan)
+		if err := db.CommandContext(ctx, q).All(&recs); err != nil {
+			return nil, err
+		}
+		for _, id := range recs {
+			if rec, ok := id.(ORecord); ok {
+				db.cache.Put(rec)
+				out[rec.GetIdentity()] = rec
+			}
+		}
+	}
+	return out, nil
 }
 
 // UpdateRecord updates given record in a database. Record version will be changed after the call.
 func (db *Database) UpdateRecord(rec ORecord) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
+	return db.UpdateRecordContext(context.Background(), rec)
+}
+
+// UpdateRecordContext is like UpdateRecord, but allows to cancel the request with a context.
+func (db *Database) UpdateRecordContext(ctx context.Context, rec ORecord) error {
+	err := db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.UpdateRecordContext(ctx, rec)
+	})
+	if doc, ok := rec.(*Document); ok && err == nil {
+		doc.updateSource()
 	}
-	defer db.pool.putConn(conn)
-	return conn.UpdateRecord(rec)
+	return err
+}
+
+// RidBagFirstKey returns the first link stored in tree-based RidBag, or an invalid RID if the tree is empty.
+func (db *Database) RidBagFirstKey(ptr BonsaiPointer) (RID, error) {
+	return db.RidBagFirstKeyContext(context.Background(), ptr)
+}
+
+// RidBagFirstKeyContext is like RidBagFirstKey, but allows to cancel the request with a context.
+func (db *Database) RidBagFirstKeyContext(ctx context.Context, ptr BonsaiPointer) (RID, error) {
+	var rid RID
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		rid, err = conn.RidBagFirstKeyContext(ctx, ptr)
+		return
+	})
+	return rid, err
+}
+
+// RidBagEntriesMajor returns up to pageSize links from tree-based RidBag that follow given key.
+func (db *Database) RidBagEntriesMajor(ptr BonsaiPointer, key RID, inclusive bool, pageSize int) ([]RidBagEntry, error) {
+	return db.RidBagEntriesMajorContext(context.Background(), ptr, key, inclusive, pageSize)
+}
+
+// RidBagEntriesMajorContext is like RidBagEntriesMajor, but allows to cancel the request with a context.
+func (db *Database) RidBagEntriesMajorContext(ctx context.Context, ptr BonsaiPointer, key RID, inclusive bool, pageSize int) ([]RidBagEntry, error) {
+	var entries []RidBagEntry
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		entries, err = conn.RidBagEntriesMajorContext(ctx, ptr, key, inclusive, pageSize)
+		return
+	})
+	return entries, err
+}
+
+// RidBagSize returns the size of tree-based RidBag with pending changes applied.
+func (db *Database) RidBagSize(ptr BonsaiPointer, changes RidBagChanges) (int, error) {
+	return db.RidBagSizeContext(context.Background(), ptr, changes)
+}
+
+// RidBagSizeContext is like RidBagSize, but allows to cancel the request with a context.
+func (db *Database) RidBagSizeContext(ctx context.Context, ptr BonsaiPointer, changes RidBagChanges) (int, error) {
+	var n int
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		n, err = conn.RidBagSizeContext(ctx, ptr, changes)
+		return
+	})
+	return n, err
 }
 
 // CountRecords returns total records count.
 func (db *Database) CountRecords() (int64, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
+	return db.CountRecordsContext(context.Background())
+}
+
+// CountRecordsContext is like CountRecords, but allows to cancel the request with a context.
+func (db *Database) CountRecordsContext(ctx context.Context) (int64, error) {
+	var n int64
+	err := db.withConn(ctx, true, func(conn DBSession) (err error) {
+		n, err = conn.CountRecordsContext(ctx)
+		return
+	})
+	return n, err
+}
+
+// isReadOnly checks if command only reads the data, thus can be sent to any cluster node.
+func isReadOnly(cmd OCommandRequestText) bool {
+	switch cmd.(type) {
+	case SQLQuery, *SQLQuery:
+		return true
 	}
-	defer db.pool.putConn(conn)
-	return conn.CountRecords()
+	return false
 }
 
 // Command executes command against current database. Example:
@@ -403,15 +877,41 @@ func (db *Database) CountRecords() (int64, error) {
 //		result := db.Command(NewSQLQuery("SELECT FROM V WHERE id = ?", id).Limit(10))
 //
 func (db *Database) Command(cmd OCommandRequestText) Results {
-	conn, err := db.pool.getConn()
+	return db.CommandContext(context.Background(), cmd)
+}
+
+// CommandContext is like Command, but allows to cancel the request with a context.
+// Cancellation also stops retries on concurrent modification errors.
+//
+// Collections of records returned by queries (SQLQuery) are decoded lazily, as Next is called, so the session is held
+// until all results are read or results are closed. Context is also used to read the records.
+func (db *Database) CommandContext(ctx context.Context, cmd OCommandRequestText) Results {
+	conn, release, err := db.getConn(ctx, isReadOnly(cmd))
 	if err != nil {
-		return errorResult{err: err}
+		return errorResult{err: convertError(err)}
 	}
-	defer db.pool.putConn(conn)
-	var result interface{}
-	for i := 0; concurrentRetries < 0 || i < concurrentRetries; i++ {
-		result, err = conn.Command(cmd)
+	streamer, stream := asCommandStreamer(conn)
+	// only queries are streamed, since results of other commands are rarely iterated;
+	// queries with fetch plans are read at once to resolve links
+	stream = stream && isReadOnly(cmd) && !isSchemaCommand(cmd) && !hasFetchPlan(cmd)
+	var (
+		result interface{}
+		cs     CommandStream
+	)
+	retries := db.cli.retryCount()
+	for i := 0; retries < 0 || i < retries; i++ {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+		if stream {
+			cs, err = streamer.CommandStreamContext(ctx, cmd)
+		} else {
+			result, err = conn.CommandContext(ctx, cmd)
+		}
 		err = convertError(err)
+		if err == nil && isSchemaCommand(cmd) {
+			db.schemaChanged(ctx, conn) // command succeeded, so reload errors are not reported
+		}
 		switch err.(type) {
 		case ErrConcurrentModification:
 			continue
@@ -419,11 +919,53 @@ func (db *Database) Command(cmd OCommandRequestText) Results {
 		break
 	}
 	if err != nil {
-		return errorResult{err: convertError(err)}
+		release(err)
+		return errorResult{err: err}
+	}
+	if cs != nil {
+		if v, ok := cs.Value(); ok {
+			result = v
+		} else {
+			return &streamResults{ctx: ctx, db: db, stream: cs, release: release}
+		}
 	}
+	release(nil)
+	db.bind(result)
 	return newResults(result)
 }
 
+// commandStreamer is implemented by sessions that can decode command results incrementally.
+type commandStreamer interface {
+	CommandStreamContext(ctx context.Context, cmd CustomSerializable) (CommandStream, error)
+}
+
+// asCommandStreamer checks if session (possibly wrapped by the client) supports streaming of command results.
+func asCommandStreamer(conn DBSession) (commandStreamer, bool) {
+	for {
+		switch c := conn.(type) {
+		case commandStreamer:
+			return c, true
+		case *pooledConn:
+			conn = c.DBSession
+		case sessionAndConn:
+			conn = c.DBSession
+		default:
+			return nil, false
+		}
+	}
+}
+
+// hasFetchPlan checks if command is a query with a custom fetch plan.
+func hasFetchPlan(cmd OCommandRequestText) bool {
+	switch q := cmd.(type) {
+	case SQLQuery:
+		return q.plan != ""
+	case *SQLQuery:
+		return q.plan != ""
+	}
+	return false
+}
+
 func sqlEscape(s string) string { // TODO: get rid of it
 	s = strings.Replace(s, `\`, `\\`, -1)
 	s = strings.Replace(s, `"`, `\"`, -1)
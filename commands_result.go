@@ -1,13 +1,16 @@
 package orient
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"reflect"
 )
 
 var (
 	_ Results = errorResult{}
 	_ Results = (*unknownResult)(nil)
+	_ Results = (*streamResults)(nil)
 )
 
 // Results is an interface for database command results. Must be closed.
@@ -77,32 +80,161 @@ type unknownResult struct {
 	result interface{}
 }
 
-func (r *unknownResult) Err() error                     { return r.err }
-func (r *unknownResult) Close() error                   { return r.err }
-func (r *unknownResult) Next(result interface{}) bool { // TODO: implement
-	if r.parsed {
+func (r *unknownResult) Err() error   { return r.err }
+func (r *unknownResult) Close() error { return r.err }
+
+// Next returns records of collection results one by one. Other results are returned as a single value.
+func (r *unknownResult) Next(result interface{}) bool {
+	if r.err != nil {
+		return false
+	}
+	var val interface{}
+	if recs, ok := r.result.([]OIdentifiable); ok {
+		if len(recs) == 0 {
+			return false
+		}
+		val, r.result = recs[0], recs[1:]
+	} else if r.parsed || r.result == nil {
 		return false
+	} else {
+		val = r.result
 	}
 	r.parsed = true
-	r.All(result)
-	return false
+	if err := convertResult(result, val); err != nil {
+		r.err = err
+		return false
+	}
+	return true
 }
+
 func (r *unknownResult) All(result interface{}) error {
 	//	if r.parsed {
 	//		return fmt.Errorf("results are already parsed")
 	//	}
 	//	r.parsed = true
 
-	// check for pointer
+	return convertResult(result, r.result)
+}
+
+// streamResults reads records of a command result one by one from the connection. Session is held until all records
+// are read or results are closed.
+type streamResults struct {
+	ctx     context.Context
+	db      *Database
+	stream  CommandStream
+	release func(err error)
+
+	started bool            // records were requested with Next, All or Err
+	buf     []OIdentifiable // records read by Err before iteration
+
+	err  error
+	done bool // session was released
+}
+
+// Err returns an error of the command. If records were not requested yet, they are read to memory and the session
+// is released, so results that are only checked for errors do not hold the session.
+func (r *streamResults) Err() error {
+	if !r.started {
+		r.started = true
+		for {
+			rec, ok := r.read()
+			if !ok {
+				break
+			}
+			r.buf = append(r.buf, rec)
+		}
+	}
+	return r.err
+}
+
+// fetch returns the next record, either buffered by Err or read from the stream.
+func (r *streamResults) fetch() (OIdentifiable, bool) {
+	r.started = true
+	if r.err != nil {
+		return nil, false
+	} else if len(r.buf) != 0 {
+		rec := r.buf[0]
+		r.buf = r.buf[1:]
+		return rec, true
+	}
+	return r.read()
+}
+
+// read reads the next record from the stream, releasing the session after the last one.
+func (r *streamResults) read() (OIdentifiable, bool) {
+	if r.done || r.err != nil {
+		return nil, false
+	}
+	rec, err := r.stream.NextContext(r.ctx)
+	if err == io.EOF {
+		r.finish(nil)
+		return nil, false
+	} else if err != nil {
+		r.err = convertError(err)
+		r.finish(err)
+		return nil, false
+	}
+	r.db.bind(rec)
+	return rec, true
+}
+
+func (r *streamResults) finish(err error) {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.release(err)
+}
+
+func (r *streamResults) Next(result interface{}) bool {
+	rec, ok := r.fetch()
+	if !ok {
+		return false
+	}
+	if err := convertResult(result, rec); err != nil {
+		r.err = err
+		r.Close()
+		return false
+	}
+	return true
+}
+
+func (r *streamResults) All(result interface{}) error {
+	all := []OIdentifiable{}
+	for {
+		rec, ok := r.fetch()
+		if !ok {
+			break
+		}
+		all = append(all, rec)
+	}
+	if r.err == nil {
+		r.err = convertResult(result, all)
+	}
+	r.Close()
+	return r.err
+}
+
+func (r *streamResults) Close() error {
+	if !r.done {
+		err := r.stream.Close()
+		r.finish(err)
+		if r.err == nil {
+			r.err = convertError(err)
+		}
+	}
+	return r.err
+}
+
+// convertResult stores value to result, which must be a pointer.
+func convertResult(result interface{}, val interface{}) error {
 	targ := reflect.ValueOf(result)
 	if targ.Kind() != reflect.Ptr {
 		return fmt.Errorf("result is not a pointer: %T", result)
 	} else if targ.IsNil() {
 		return fmt.Errorf("nil result pointer")
 	}
-	targ = targ.Elem()
-
-	return convertTypes(targ, reflect.ValueOf(r.result))
+	return convertTypes(targ.Elem(), reflect.ValueOf(val))
 }
 
 type ErrUnsupportedConversion struct {
@@ -167,6 +299,8 @@ func convertTypes(targ, src reflect.Value) error {
 		}
 		if src.Kind() == reflect.Map {
 			return mapToStruct(src.Interface(), targ.Addr().Interface())
+		} else if doc, ok := src.Interface().(*Document); ok && doc != nil {
+			return mapToStruct(doc, targ.Addr().Interface()) // decoder hooks need the document for record metadata
 		}
 	} else if targ.Kind() == reflect.Slice {
 		if src.Kind() == reflect.Slice { // slice into slice
@@ -54,3 +54,25 @@ func TestResultsInnerStruct(t *testing.T) {
 	var dst *Item
 	testResults(t, doc, &dst, &Item{One: one, Inner: []Inner{one, two}})
 }
+
+func TestResultsNext(t *testing.T) {
+	r := newResults([]OIdentifiable{RID{ClusterID: 1, ClusterPos: 1}, RID{ClusterID: 1, ClusterPos: 2}})
+	var rids []RID
+	var rid RID
+	for r.Next(&rid) {
+		rids = append(rids, rid)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	} else if len(rids) != 2 || rids[1].ClusterPos != 2 {
+		t.Fatalf("unexpected results: %v", rids)
+	}
+
+	r = newResults(3)
+	var n int
+	if !r.Next(&n) || n != 3 {
+		t.Fatalf("unexpected result: %v, %v", n, r.Err())
+	} else if r.Next(&n) {
+		t.Fatal("single value returned twice")
+	}
+}
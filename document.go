@@ -7,7 +7,8 @@ import (
 
 	//	"database/sql/driver"
 	"reflect"
-	"strings"
+
+	"github.com/nu7hatch/gouuid"
 )
 
 var (
@@ -22,6 +23,9 @@ type DocEntry struct {
 	Name  string
 	Type  OType
 	Value interface{}
+
+	linkedType    OType // type of items of embedded collection
+	hasLinkedType bool
 }
 
 func (fld *DocEntry) String() string {
@@ -39,6 +43,8 @@ type Document struct {
 	classname   string // TODO: probably needs to change *OClass (once that is built)
 	dirty       bool
 	ser         RecordSerializer
+	db          *Database     // database the document was loaded from
+	src         reflect.Value // struct the document was created from; metadata is written back to it after saving
 }
 
 func (doc *Document) ClassName() string { return doc.classname }
@@ -207,6 +213,15 @@ func (doc *Document) SetFieldWithType(name string, val interface{}, fieldType OT
 	return doc.AddField(name, fld)
 }
 
+// SetFieldWithLinkedType is like SetFieldWithType, but also sets a type of items for embedded collections.
+// The same *Document is returned to allow call chaining.
+func (doc *Document) SetFieldWithLinkedType(name string, val interface{}, fieldType, linkedType OType) *Document {
+	doc.SetFieldWithType(name, val, fieldType)
+	fld := doc.fields[name]
+	fld.linkedType, fld.hasLinkedType = linkedType, true
+	return doc
+}
+
 func (doc *Document) RawContainsField(name string) bool {
 	doc.ensureDecoded()
 	return doc != nil && doc.fields[name] != nil
@@ -323,8 +338,31 @@ func (doc *Document) SetSerializer(ser RecordSerializer) {
 }
 func (doc *Document) Fill(rid RID, version int, content []byte) error {
 	doc.serialized = doc.serialized || doc.BytesRecord.Data == nil || bytes.Compare(content, doc.BytesRecord.Data) != 0
+	if !doc.serialized { // document was saved, so changes of RidBags were sent to the server
+		for _, fld := range doc.fields {
+			if bag, ok := fld.Value.(*RidBag); ok {
+				bag.saved()
+			}
+		}
+	}
 	return doc.BytesRecord.Fill(rid, version, content)
 }
+
+// SetRidBagPointers updates tree-based RidBags of the document with pointers returned by the server after
+// the document is saved. Pointers are matched by RidBag UUID. It must be called before Fill.
+func (doc *Document) SetRidBagPointers(ptrs map[uuid.UUID]BonsaiPointer) {
+	if len(ptrs) == 0 {
+		return
+	}
+	for _, fld := range doc.fields {
+		if bag, ok := fld.Value.(*RidBag); ok {
+			if ptr, ok := ptrs[bag.id]; ok {
+				bag.setPointer(ptr)
+			}
+		}
+	}
+}
+
 func (doc *Document) RecordType() RecordType { return RecordTypeDocument }
 
 // ToDocument implement DocumentSerializable interface. In this case, Document just returns itself.
@@ -333,12 +371,9 @@ func (doc *Document) ToDocument() (*Document, error) {
 }
 
 // ToStruct fills provided struct with content of a Document. Argument must be a pointer to structure.
+// Field names and links are mapped according to FieldTagName tags.
 func (doc *Document) ToStruct(o interface{}) error {
-	mp, err := doc.ToMap()
-	if err != nil {
-		return err
-	}
-	return mapToStruct(mp, o)
+	return mapToStruct(doc, o)
 }
 
 func (doc *Document) setFieldsFrom(rv reflect.Value) error {
@@ -350,21 +385,26 @@ func (doc *Document) setFieldsFrom(rv reflect.Value) error {
 			if !isExported(fld.Name) {
 				continue
 			}
-			name := fld.Name
-			tags := strings.Split(fld.Tag.Get(TagName), ",")
-			if tags[0] == "-" {
+			tag, err := parseFieldTag(fld)
+			if err != nil {
+				return fmt.Errorf("field '%s': %s", fld.Name, err)
+			} else if tag.skip {
 				continue
 			}
-			if tags[0] != "" {
-				name = tags[0]
+			fv := rv.Field(i)
+			switch tag.name {
+			case metaRID, metaVersion, metaClass:
+				doc.setMetaField(tag, fv)
+				continue
 			}
-			squash := (len(tags) > 1 && tags[1] == "squash") // TODO: change default behavior to squash if field is anonymous
-			if squash {
-				if err := doc.setFieldsFrom(rv.Field(i)); err != nil {
-					return fmt.Errorf("field '%s': %s", name, err)
+			if tag.squash {
+				if err := doc.setFieldsFrom(fv); err != nil {
+					return fmt.Errorf("field '%s': %s", tag.name, err)
+				}
+			} else if !tag.omitEmpty || !isEmptyValue(fv) {
+				if err := doc.setTaggedField(tag, fv); err != nil {
+					return fmt.Errorf("field '%s': %s", tag.name, err)
 				}
-			} else {
-				doc.SetField(name, rv.Field(i).Interface())
 			}
 		}
 		return nil
@@ -380,8 +420,9 @@ func (doc *Document) setFieldsFrom(rv reflect.Value) error {
 
 // From sets Document fields to values provided in argument (which can be a map or a struct).
 //
-// From uses TagName field tag to determine field name and conversion parameters.
-// For now it supports only one special tag parameter: ",squash" which can be used to inline fields into parent struct.
+// From uses FieldTagName field tag to determine field name, type and conversion parameters. For fields without it,
+// TagName tag is used; it supports only one special tag parameter: ",squash" which can be used to inline fields into
+// parent struct.
 func (doc *Document) From(o interface{}) error {
 	// TODO: clear fields and serialized data
 	if o == nil {
@@ -391,6 +432,10 @@ func (doc *Document) From(o interface{}) error {
 	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
 		rv = rv.Elem()
 	}
+	doc.src = reflect.Value{}
+	if rv.Kind() == reflect.Struct && rv.CanSet() {
+		doc.src = rv
+	}
 	return doc.setFieldsFrom(rv)
 }
 
@@ -0,0 +1,162 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrNotBound is returned when linked records of a document are requested, but document is not bound to a database.
+var ErrNotBound = fmt.Errorf("document is not bound to a database")
+
+// Bind binds document to a database, so linked records can be loaded on demand (see Linked).
+// Documents returned by Database methods are bound automatically.
+func (doc *Document) Bind(db *Database) {
+	doc.db = db
+}
+
+// DB returns a database the document is bound to, or nil.
+func (doc *Document) DB() *Database {
+	return doc.db
+}
+
+// Linked returns a document referenced by a LINK field. Linked record is taken from the records cache,
+// or is loaded from the database the document is bound to. Loaded document replaces the link in the field,
+// so following calls return the same document without additional requests.
+//
+// Nil is returned if field is not set, or if linked record does not exist.
+func (doc *Document) Linked(field string) (*Document, error) {
+	return doc.LinkedContext(context.Background(), field)
+}
+
+// LinkedContext is like Linked, but allows to cancel the request with a context.
+func (doc *Document) LinkedContext(ctx context.Context, field string) (*Document, error) {
+	fld := doc.GetField(field)
+	if fld == nil || fld.Value == nil {
+		return nil, nil
+	}
+	id, ok := fld.Value.(OIdentifiable)
+	if !ok {
+		return nil, fmt.Errorf("field %q is not a link: %T", field, fld.Value)
+	}
+	if ldoc, ok := id.GetRecord().(*Document); ok {
+		doc.bindLinked(ldoc)
+		return ldoc, nil
+	}
+	recs, err := doc.loadLinked(ctx, []OIdentifiable{id})
+	if err != nil {
+		return nil, err
+	}
+	ldoc := recs[id.GetIdentity()]
+	if ldoc != nil {
+		fld.Value = ldoc
+	}
+	return ldoc, nil
+}
+
+// LinkedList returns documents referenced by LINKLIST, LINKSET or LINKBAG field. All records that are not in
+// the records cache are loaded in a single request. Loaded documents replace links in LINKLIST and LINKSET fields.
+//
+// Links to records that do not exist are skipped.
+func (doc *Document) LinkedList(field string) ([]*Document, error) {
+	return doc.LinkedListContext(context.Background(), field)
+}
+
+// LinkedListContext is like LinkedList, but allows to cancel the request with a context.
+func (doc *Document) LinkedListContext(ctx context.Context, field string) ([]*Document, error) {
+	fld := doc.GetField(field)
+	if fld == nil || fld.Value == nil {
+		return nil, nil
+	}
+	var ids []OIdentifiable
+	switch v := fld.Value.(type) {
+	case []OIdentifiable:
+		ids = v
+	case *RidBag:
+		it := v.Iterator(doc.db)
+		for it.NextContext(ctx) {
+			ids = append(ids, it.Link())
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("field %q is not a list of links: %T", field, fld.Value)
+	}
+	recs, err := doc.loadLinked(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Document, 0, len(ids))
+	for i, id := range ids {
+		if id == nil {
+			continue
+		}
+		ldoc, ok := id.GetRecord().(*Document)
+		if !ok {
+			ldoc = recs[id.GetIdentity()]
+		}
+		if ldoc == nil {
+			continue
+		}
+		ids[i] = ldoc
+		out = append(out, ldoc)
+	}
+	return out, nil
+}
+
+func (doc *Document) bindLinked(ldoc *Document) {
+	if ldoc.db == nil {
+		ldoc.db = doc.db
+	}
+}
+
+// loadLinked returns documents for links that are not resolved yet. Records are taken from cache,
+// or are loaded from database in a single request.
+func (doc *Document) loadLinked(ctx context.Context, ids []OIdentifiable) (map[RID]*Document, error) {
+	var rids []RID
+	for _, id := range ids {
+		if id == nil {
+			continue
+		} else if ldoc, ok := id.GetRecord().(*Document); ok {
+			doc.bindLinked(ldoc)
+			continue
+		}
+		rids = append(rids, id.GetIdentity())
+	}
+	out := make(map[RID]*Document, len(rids))
+	if len(rids) == 0 {
+		return out, nil
+	} else if doc.db == nil {
+		return nil, ErrNotBound
+	}
+	recs, err := doc.db.loadRecords(ctx, rids, DefaultFetchPlan, false)
+	if err != nil {
+		return nil, err
+	}
+	for rid, rec := range recs {
+		if ldoc, ok := rec.(*Document); ok {
+			doc.bindLinked(ldoc)
+			out[rid] = ldoc
+		}
+	}
+	return out, nil
+}
+
+// bind binds all documents in the result to the database.
+func (db *Database) bind(result interface{}) {
+	if db == nil {
+		return
+	}
+	switch res := result.(type) {
+	case *Document:
+		if res != nil {
+			res.db = db
+		}
+	case []OIdentifiable:
+		for _, rec := range res {
+			if doc, ok := rec.(*Document); ok && doc != nil {
+				doc.db = db
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package orient
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// linkSession serves records from memory and counts requests.
+type linkSession struct {
+	testSession
+	recs     map[RID]*Document
+	loads    int
+	commands int
+}
+
+func (s *linkSession) copyOf(rid RID) *Document {
+	doc, ok := s.recs[rid]
+	if !ok {
+		return nil
+	}
+	data, _ := doc.Content()
+	out := NewEmptyDocument()
+	out.Fill(rid, 1, data)
+	return out
+}
+
+func (s *linkSession) GetRecordByRIDContext(ctx context.Context, rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, error) {
+	s.loads++
+	if doc := s.copyOf(rid); doc != nil {
+		return doc, nil
+	}
+	return nil, nil
+}
+
+func (s *linkSession) CommandContext(ctx context.Context, cmd CustomSerializable) (interface{}, error) {
+	s.commands++
+	text := cmd.(SQLQuery).GetText()
+	var out []OIdentifiable
+	for rid := range s.recs {
+		if strings.Contains(text, rid.String()+",") || strings.Contains(text, rid.String()+"]") {
+			out = append(out, s.copyOf(rid))
+		}
+	}
+	return out, nil
+}
+
+func TestDocumentLinked(t *testing.T) {
+	city := NewDocument("City").SetField("name", "Kyiv")
+	addr := NewDocument("Address").SetField("city", NewRID(3, 1))
+	sess := &linkSession{recs: map[RID]*Document{
+		NewRID(2, 1): addr,
+		NewRID(3, 1): city,
+		NewRID(4, 1): NewDocument("Pet").SetField("name", "a"),
+		NewRID(4, 2): NewDocument("Pet").SetField("name", "b"),
+	}}
+	db := newTestDB(t, func() DBSession { return sess })
+	db.cache = NewRecordCache(10)
+
+	person := NewDocument("Person")
+	person.SetField("address", NewRID(2, 1))
+	person.SetField("pets", []OIdentifiable{NewRID(4, 1), NewRID(4, 9), NewRID(4, 2)})
+	if _, err := person.Linked("address"); err != ErrNotBound {
+		t.Fatalf("expected ErrNotBound, got %v", err)
+	}
+	person.Bind(db)
+
+	a, err := person.Linked("address")
+	if err != nil {
+		t.Fatal(err)
+	} else if a == nil || a.DB() != db {
+		t.Fatalf("unexpected address: %v", a)
+	}
+	c, err := a.Linked("city")
+	if err != nil {
+		t.Fatal(err)
+	} else if c == nil || c.GetField("name").Value != "Kyiv" {
+		t.Fatalf("unexpected city: %v", c)
+	}
+	if a2, _ := person.Linked("address"); a2 != a {
+		t.Fatal("linked document was not stored to the field")
+	} else if sess.loads != 2 {
+		t.Fatalf("expected 2 loads, got %d", sess.loads)
+	}
+
+	db.cache.Put(sess.copyOf(NewRID(4, 1)))
+	pets, err := person.LinkedList("pets")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pets) != 2 || pets[0].GetField("name").Value != "a" || pets[1].GetField("name").Value != "b" {
+		t.Fatalf("unexpected pets: %v", pets)
+	} else if sess.commands != 1 || sess.loads != 2 {
+		t.Fatalf("expected a single batch request, got %d commands and %d loads", sess.commands, sess.loads)
+	}
+	if _, err = person.LinkedList("pets"); err != nil {
+		t.Fatal(err)
+	} else if sess.commands != 1 || sess.loads != 3 { // only missing record is requested again
+		t.Fatalf("resolved links were loaded again: %d commands and %d loads", sess.commands, sess.loads)
+	}
+}
+
+func TestLoadRecords(t *testing.T) {
+	sess := &linkSession{recs: make(map[RID]*Document)}
+	var rids []RID
+	for i := 0; i < loadRecordsChunk*2+10; i++ {
+		rid := NewRID(5, int64(i))
+		sess.recs[rid] = NewDocument("V").SetField("i", int32(i))
+		rids = append(rids, rid)
+	}
+	db := newTestDB(t, func() DBSession { return sess })
+	db.cache = NewRecordCache(20)
+
+	rids = append([]RID{NewRID(5, 999), rids[3]}, rids...)
+	recs, err := db.LoadRecords(rids, DefaultFetchPlan)
+	if e, ok := err.(ErrMissingRecords); !ok || len(e.RIDs) != 1 || e.RIDs[0] != NewRID(5, 999) {
+		t.Fatalf("expected missing record error, got %v", err)
+	} else if len(recs) != len(rids) || recs[0] != nil {
+		t.Fatalf("unexpected records: %v", recs)
+	} else if sess.commands != 3 {
+		t.Fatalf("expected 3 requests, got %d", sess.commands)
+	}
+	for i, rec := range recs[1:] {
+		if rec.GetIdentity() != rids[i+1] {
+			t.Fatalf("wrong order at %d: %v != %v", i+1, rec.GetIdentity(), rids[i+1])
+		} else if doc := rec.(*Document); doc.DB() != db {
+			t.Fatal("document is not bound")
+		}
+	}
+
+	// records of the last batch are cached
+	if _, err = db.LoadRecords(rids[len(rids)-10:], DefaultFetchPlan); err != nil {
+		t.Fatal(err)
+	} else if sess.commands != 3 || sess.loads != 0 {
+		t.Fatalf("cached records were loaded again: %d commands and %d loads", sess.commands, sess.loads)
+	}
+}
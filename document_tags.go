@@ -0,0 +1,421 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldTagName is a name of a struct tag that controls mapping of struct fields to document fields, in both directions
+// (see Document.From and Document.ToStruct). Tag consists of a field name and a list of options:
+//
+//	type Person struct {
+//		Name    string   `orient:"name"`
+//		Tags    []string `orient:"tags,type=EMBEDDEDSET,linkedType=STRING,omitempty"`
+//		Manager *Person  `orient:"manager,link"`
+//		Secret  string   `orient:"-"`
+//	}
+//
+// Supported options are:
+//
+//	type=T        - OrientDB type of the field, as accepted by OTypeFromString; inferred from value by default
+//	linkedType=T  - type of items of embedded collection
+//	omitempty     - skip the field if it has a zero value
+//	link          - store a struct (or a slice of structs) as a link to other record instead of embedding it;
+//	                RID of the record is taken from @rid field, or from the first struct field of RID type
+//
+// Reserved names map struct fields to record metadata instead of document fields:
+//
+//	type Person struct {
+//		RID     orient.RID `orient:"@rid"`
+//		Version int        `orient:"@version"`
+//		Class   string     `orient:"@class"`
+//	}
+//
+// Zero values of these fields are ignored by From. If a document was created from a pointer to struct, RID and
+// version are written back to the struct after Database.CreateRecord and Database.UpdateRecord, so the version
+// can be used for optimistic locking of the next update.
+//
+// Fields without this tag are mapped according to TagName tag, or by their names.
+const FieldTagName = "orient"
+
+// Names of record metadata, as used by FieldTagName and Document.ToMap.
+const (
+	metaRID     = "@rid"
+	metaVersion = "@version"
+	metaClass   = "@class"
+)
+
+// fieldTag is a parsed mapping of a struct field.
+type fieldTag struct {
+	name       string
+	skip       bool
+	squash     bool
+	tp         OType // UNKNOWN if not set
+	linkedType OType // UNKNOWN if not set
+	omitEmpty  bool
+	link       bool
+}
+
+// parseFieldTag parses FieldTagName tag of a struct field. If it's not set, name and squash option are taken from TagName tag.
+func parseFieldTag(fld reflect.StructField) (fieldTag, error) {
+	tag := fieldTag{name: fld.Name, tp: UNKNOWN, linkedType: UNKNOWN}
+	ms := strings.Split(fld.Tag.Get(TagName), ",")
+	if ms[0] == "-" {
+		tag.skip = true
+	} else if ms[0] != "" {
+		tag.name = ms[0]
+	}
+	tag.squash = len(ms) > 1 && ms[1] == "squash" // TODO: change default behavior to squash if field is anonymous
+	s, ok := fld.Tag.Lookup(FieldTagName)
+	if !ok {
+		return tag, nil
+	}
+	parts := strings.Split(s, ",")
+	tag.skip = parts[0] == "-"
+	if parts[0] != "" && !tag.skip {
+		tag.name = parts[0]
+	}
+	for _, opt := range parts[1:] {
+		var err error
+		key, val := opt, ""
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			key, val = opt[:i], opt[i+1:]
+		}
+		switch key {
+		case "type":
+			tag.tp, err = parseOType(val)
+		case "linkedType":
+			tag.linkedType, err = parseOType(val)
+		case "omitempty":
+			tag.omitEmpty = true
+		case "link":
+			tag.link = true
+		default:
+			err = fmt.Errorf("unknown option %q of %s tag", opt, FieldTagName)
+		}
+		if err != nil {
+			return tag, err
+		}
+	}
+	switch tag.name {
+	case metaRID, metaVersion, metaClass:
+		if err := checkMetaField(tag, fld.Type); err != nil {
+			return tag, err
+		}
+	}
+	switch tag.tp {
+	case UNKNOWN, LINK, LINKLIST, LINKSET:
+	default:
+		if tag.link {
+			return tag, fmt.Errorf("link cannot be stored as %v", tag.tp)
+		}
+	}
+	switch tag.tp {
+	case UNKNOWN, EMBEDDEDLIST, EMBEDDEDSET, EMBEDDEDMAP:
+	default:
+		if tag.linkedType != UNKNOWN {
+			return tag, fmt.Errorf("linked type is set for %v field", tag.tp)
+		}
+	}
+	return tag, nil
+}
+
+// checkMetaField checks type and options of a field that holds record metadata.
+func checkMetaField(tag fieldTag, t reflect.Type) error {
+	if tag.tp != UNKNOWN || tag.linkedType != UNKNOWN || tag.link || tag.omitEmpty {
+		return fmt.Errorf("options are not supported for %s", tag.name)
+	}
+	var ok bool
+	switch tag.name {
+	case metaRID:
+		ok = t == reflRIDType
+	case metaVersion:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			ok = true
+		}
+	case metaClass:
+		ok = t.Kind() == reflect.String
+	}
+	if !ok {
+		return fmt.Errorf("%s cannot be stored to %v", tag.name, t)
+	}
+	return nil
+}
+
+// setMetaField sets record metadata from a struct field. Zero values are ignored.
+func (doc *Document) setMetaField(tag fieldTag, fv reflect.Value) {
+	switch tag.name {
+	case metaRID:
+		if rid := fv.Interface().(RID); rid != (RID{}) {
+			doc.RID = rid
+		}
+	case metaVersion:
+		if vers := fv.Int(); vers != 0 {
+			doc.Vers = int(vers)
+		}
+	case metaClass:
+		if class := fv.String(); class != "" {
+			doc.classname = class
+		}
+	}
+}
+
+// updateSource writes record metadata to the struct the document was created from.
+func (doc *Document) updateSource() {
+	if doc.src.IsValid() {
+		doc.setMetaFieldsOf(doc.src)
+	}
+}
+
+func (doc *Document) setMetaFieldsOf(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !isExported(fld.Name) {
+			continue
+		}
+		tag, err := parseFieldTag(fld)
+		if err != nil || tag.skip {
+			continue
+		}
+		fv := v.Field(i)
+		switch {
+		case tag.squash:
+			if fv.Kind() == reflect.Struct {
+				doc.setMetaFieldsOf(fv)
+			}
+		case tag.name == metaRID:
+			fv.Set(reflect.ValueOf(doc.RID))
+		case tag.name == metaVersion:
+			fv.SetInt(int64(doc.Vers))
+		case tag.name == metaClass:
+			fv.SetString(doc.classname)
+		}
+	}
+}
+
+// parseOType is like OTypeFromString, but returns an error for unknown types.
+func parseOType(s string) (tp OType, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unknown type: %q", s)
+		}
+	}()
+	return OTypeFromString(strings.ToUpper(s)), nil
+}
+
+// setTaggedField sets document field to a value of struct field, according to the field tag.
+func (doc *Document) setTaggedField(tag fieldTag, fv reflect.Value) error {
+	val, tp := fv.Interface(), tag.tp
+	if tag.link {
+		var err error
+		if val, err = linkValue(fv); err != nil {
+			return err
+		}
+		if _, ok := val.([]RID); ok && tp == UNKNOWN {
+			tp = LINKLIST
+		} else if tp == UNKNOWN {
+			tp = LINK
+		}
+	}
+	if tp == UNKNOWN {
+		tp = OTypeForValue(val)
+	}
+	if tag.linkedType != UNKNOWN {
+		doc.SetFieldWithLinkedType(tag.name, val, tp, tag.linkedType)
+	} else {
+		doc.SetFieldWithType(tag.name, val, tp)
+	}
+	return nil
+}
+
+var reflRIDType = reflect.TypeOf(RID{})
+
+// structRID returns RID of a record stored in a struct (see ridField).
+func structRID(v reflect.Value) (RID, bool) {
+	if i, ok := ridField(v.Type()); ok {
+		return v.Field(i).Interface().(RID), true
+	}
+	return RID{}, false
+}
+
+// ridField returns an index of struct field with RID of a record: either a field with @rid tag,
+// or the first field of RID type.
+func ridField(t reflect.Type) (int, bool) {
+	first := -1
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if fld.Type != reflRIDType {
+			continue
+		} else if tag, err := parseFieldTag(fld); err == nil && tag.name == metaRID {
+			return i, true
+		} else if first < 0 {
+			first = i
+		}
+	}
+	return first, first >= 0
+}
+
+// linkValue converts a struct, a record or a slice of them to RIDs. Nil values are returned as nil.
+func linkValue(v reflect.Value) (interface{}, error) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, nil
+	}
+	if id, ok := v.Interface().(OIdentifiable); ok {
+		return id.GetIdentity(), nil
+	}
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		if rid, ok := structRID(v); ok {
+			return rid, nil
+		}
+		return nil, fmt.Errorf("%v has no RID field to link to", v.Type())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		rids := make([]RID, v.Len())
+		for i := range rids {
+			l, err := linkValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			} else if l == nil {
+				rids[i] = nilRID
+			} else {
+				rids[i] = l.(RID)
+			}
+		}
+		return rids, nil
+	}
+	return nil, fmt.Errorf("%v cannot be stored as a link", v.Type())
+}
+
+// fieldTagsHookFunc renames document fields in a map according to FieldTagName tags of a target struct, so the map
+// decoder can find them by struct field names. Links are converted to structs with only RID field set.
+// Documents are converted to maps, including record version for @version field.
+func fieldTagsHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || !hasFieldTags(t) {
+		return data, nil
+	}
+	var mp map[string]interface{}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		mp = v
+	case *Document:
+		if v == nil {
+			return data, nil
+		}
+		var err error
+		if mp, err = v.ToMap(); err != nil {
+			return nil, err
+		} else if v.RID.IsPersistent() {
+			mp[metaVersion] = v.Vers
+		}
+	default:
+		return data, nil
+	}
+	out := make(map[string]interface{}, len(mp))
+	for k, v := range mp {
+		out[k] = v
+	}
+	if err := renameTaggedFields(t, mp, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// hasFieldTags checks if struct or any of squashed structs has fields with FieldTagName tag.
+func hasFieldTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if _, ok := fld.Tag.Lookup(FieldTagName); ok {
+			return true
+		} else if tag, err := parseFieldTag(fld); err == nil && tag.squash && fld.Type.Kind() == reflect.Struct && hasFieldTags(fld.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+func renameTaggedFields(t reflect.Type, src, dst map[string]interface{}) error {
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !isExported(fld.Name) {
+			continue
+		}
+		tag, err := parseFieldTag(fld)
+		if err != nil {
+			return fmt.Errorf("field '%s': %s", fld.Name, err)
+		}
+		if tag.squash {
+			if fld.Type.Kind() == reflect.Struct {
+				if err = renameTaggedFields(fld.Type, src, dst); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if _, ok := fld.Tag.Lookup(FieldTagName); !ok {
+			continue
+		}
+		// map decoder finds fields by TagName tag or by name, ignoring the case
+		key := fld.Name
+		if name := strings.Split(fld.Tag.Get(TagName), ",")[0]; name != "" {
+			key = name
+		}
+		for k := range dst {
+			if strings.EqualFold(k, key) && (k != tag.name || tag.skip) {
+				delete(dst, k)
+			}
+		}
+		val, ok := src[tag.name]
+		if !ok || tag.skip {
+			continue
+		}
+		if tag.link {
+			val = linkToStruct(fld.Type, val)
+		}
+		delete(dst, tag.name)
+		dst[key] = val
+	}
+	return nil
+}
+
+// linkToStruct converts links (or slices of links) to maps that can be decoded to a struct of type t (or a slice of
+// structs), by setting only RID field. Other values are returned as is.
+func linkToStruct(t reflect.Type, val interface{}) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := val.(type) {
+	case RID:
+		if t.Kind() != reflect.Struct || t == reflRIDType {
+			return val
+		}
+		if i, ok := ridField(t); ok {
+			if tag, err := parseFieldTag(t.Field(i)); err == nil {
+				return map[string]interface{}{tag.name: v} // the same name as in a document
+			}
+		}
+	case []OIdentifiable:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return val
+		}
+		out := make([]interface{}, len(v))
+		for i, id := range v {
+			if rid, ok := id.(RID); ok {
+				out[i] = linkToStruct(t.Elem(), rid)
+			} else {
+				out[i] = id
+			}
+		}
+		return out
+	}
+	return val
+}
@@ -0,0 +1,105 @@
+package orient
+
+import (
+	"context"
+	"testing"
+)
+
+// recordSession stores documents, assigning RIDs and versions like a server would.
+type recordSession struct {
+	testSession
+	recs map[RID]int // versions of stored records
+}
+
+func (s *recordSession) CreateRecordContext(ctx context.Context, rec ORecord) error {
+	doc := rec.(*Document)
+	doc.RID, doc.Vers = NewRID(9, int64(len(s.recs))), 1
+	s.recs[doc.RID] = doc.Vers
+	return nil
+}
+
+func (s *recordSession) UpdateRecordContext(ctx context.Context, rec ORecord) error {
+	doc := rec.(*Document)
+	if vers, ok := s.recs[doc.RID]; !ok {
+		return ErrRecordNotFound{RID: doc.RID}
+	} else if doc.Vers != vers {
+		return ErrConcurrentModification{}
+	}
+	doc.Vers++
+	s.recs[doc.RID] = doc.Vers
+	return nil
+}
+
+type metaItem struct {
+	ID    RID    `orient:"@rid"`
+	Vers  int    `orient:"@version"`
+	Class string `orient:"@class"`
+	Name  string `orient:"name"`
+}
+
+func TestDocumentMetaTags(t *testing.T) {
+	sess := &recordSession{recs: make(map[RID]int)}
+	db := newTestDB(t, func() DBSession { return sess })
+
+	item := metaItem{Class: "Item", Name: "one"}
+	doc := NewEmptyDocument()
+	if err := doc.From(&item); err != nil {
+		t.Fatal(err)
+	} else if doc.ClassName() != "Item" || doc.GetIdentity().IsValid() || doc.Vers != -1 {
+		t.Fatalf("wrong metadata: %v", doc)
+	} else if len(doc.FieldNames()) != 1 {
+		t.Fatalf("metadata stored as fields: %v", doc.FieldNames())
+	}
+	if err := db.CreateRecord(doc); err != nil {
+		t.Fatal(err)
+	} else if item.ID != NewRID(9, 0) || item.Vers != 1 {
+		t.Fatalf("metadata was not written back: %+v", item)
+	}
+
+	// stale version is sent to the server
+	stale := item
+	item.Name = "two"
+	doc = NewEmptyDocument()
+	doc.From(&item)
+	if err := db.UpdateRecord(doc); err != nil {
+		t.Fatal(err)
+	} else if item.Vers != 2 {
+		t.Fatalf("version was not updated: %+v", item)
+	}
+	doc = NewEmptyDocument()
+	doc.From(&stale)
+	if err := db.UpdateRecord(doc); err == nil {
+		t.Fatal("stale version was not detected")
+	} else if stale.Vers != 1 {
+		t.Fatalf("version was updated on error: %+v", stale)
+	}
+
+	var out metaItem
+	loaded := NewDocument("Item")
+	loaded.RID, loaded.Vers = NewRID(9, 0), 2
+	loaded.SetField("name", "two")
+	if err := loaded.ToStruct(&out); err != nil {
+		t.Fatal(err)
+	} else if out != item {
+		t.Fatalf("wrong struct: %+v vs %+v", out, item)
+	}
+	var outs []metaItem
+	if err := newResults([]OIdentifiable{loaded}).All(&outs); err != nil {
+		t.Fatal(err)
+	} else if len(outs) != 1 || outs[0] != item {
+		t.Fatalf("wrong results: %+v", outs)
+	}
+	var maps []map[string]interface{}
+	if err := newResults([]OIdentifiable{loaded}).All(&maps); err != nil {
+		t.Fatal(err)
+	} else if _, ok := maps[0]["@version"]; ok || maps[0]["name"] != "two" {
+		t.Fatalf("wrong map: %v", maps[0])
+	}
+
+	var bad struct {
+		Vers string `orient:"@version"`
+	}
+	if err := NewEmptyDocument().From(bad); err == nil {
+		t.Fatal("wrong type of metadata field must be reported")
+	}
+}
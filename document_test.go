@@ -1,6 +1,7 @@
 package orient_test
 
 import (
+	"bytes"
 	"gopkg.in/istreamdata/orientgo.v2"
 	"reflect"
 	"testing"
@@ -88,3 +89,74 @@ func TestDocumentFromMap(t *testing.T) {
 		t.Fatal("data differs")
 	}
 }
+
+func TestDocumentFieldTags(t *testing.T) {
+	type Owner struct {
+		ID   orient.RID
+		Name string
+	}
+	type item struct {
+		Name   string   `orient:"title"`
+		Tags   []string `orient:"tags,type=EMBEDDEDSET,linkedType=STRING"`
+		Codes  []int    `orient:"codes,linkedType=SHORT"`
+		Owner  *Owner   `orient:"owner,link"`
+		Others []Owner  `orient:"others,link,type=LINKSET"`
+		Note   string   `orient:"note,omitempty"`
+		Labels []string `orient:"labels,omitempty"`
+		Secret string   `orient:"-"`
+	}
+	a := item{
+		Name: "named", Tags: []string{"a", "b"}, Codes: []int{1, 2},
+		Owner:  &Owner{ID: orient.NewRID(5, 1), Name: "owner"},
+		Others: []Owner{{ID: orient.NewRID(5, 2)}},
+		Labels: []string{},
+		Secret: "secret",
+	}
+	doc := orient.NewDocument("Item")
+	if err := doc.From(a); err != nil {
+		t.Fatal(err)
+	}
+	for name, tp := range map[string]orient.OType{
+		"title": orient.STRING, "tags": orient.EMBEDDEDSET, "codes": orient.EMBEDDEDLIST,
+		"owner": orient.LINK, "others": orient.LINKSET,
+	} {
+		if fld := doc.GetField(name); fld == nil || fld.Type != tp {
+			t.Fatalf("wrong field %q: %v", name, fld)
+		}
+	}
+	if doc.GetField("note") != nil || doc.GetField("labels") != nil || doc.GetField("Secret") != nil || doc.GetField("Name") != nil {
+		t.Fatalf("unexpected fields: %v", doc.FieldNames())
+	} else if rid := doc.GetField("owner").Value; rid != orient.NewRID(5, 1) {
+		t.Fatalf("wrong link: %v", rid)
+	}
+
+	// serialize, so items of embedded collections are decoded according to linked type
+	buf := bytes.NewBuffer(nil)
+	ser := orient.GetDefaultRecordSerializer()
+	if err := ser.ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	doc2 := orient.NewEmptyDocument()
+	doc2.SetSerializer(ser)
+	if err := doc2.Fill(orient.NewRID(7, 1), 1, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	} else if codes := doc2.GetField("codes").Value.([]interface{}); codes[0] != int16(1) {
+		t.Fatalf("linked type was not used: %T", codes[0])
+	}
+	doc2.SetField("Name", "other") // must not be mapped to renamed field
+	var b item
+	if err := doc2.ToStruct(&b); err != nil {
+		t.Fatal(err)
+	}
+	a.Owner.Name, a.Labels, a.Secret = "", nil, ""
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("data differs:\n%+v\n%+v", a, b)
+	}
+
+	var bad struct {
+		Tags []string `orient:"tags,type=SETOFSTRINGS"`
+	}
+	if err := orient.NewEmptyDocument().From(bad); err == nil {
+		t.Fatal("unknown type must be reported")
+	}
+}
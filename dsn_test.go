@@ -0,0 +1,82 @@
+package orient
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestParseDsn(t *testing.T) {
+	cfg, err := parseDsn("admin@secret:h1:2424;h2/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.user != "admin" || cfg.pass != "secret" || cfg.addr != "h1:2424;h2" || cfg.dbname != "test" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	} else if cfg.opts.TLSConfig != nil {
+		t.Fatal("TLS should not be enabled by default")
+	}
+	if _, err = parseDsn("admin:127.0.0.1/test"); err == nil {
+		t.Fatal("expected error for dsn without user")
+	}
+}
+
+func TestParseDsnTLS(t *testing.T) {
+	conf := &tls.Config{ServerName: "orient"}
+	if err := RegisterTLSConfig("custom", conf); err != nil {
+		t.Fatal(err)
+	}
+	defer DeregisterTLSConfig("custom")
+
+	cfg, err := parseDsn("admin@admin:127.0.0.1/test?tls=custom")
+	if err != nil {
+		t.Fatal(err)
+	} else if cfg.dbname != "test" || cfg.opts.TLSConfig != conf {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	cfg, err = parseDsn("admin@admin:127.0.0.1/test?tls=skip-verify")
+	if err != nil {
+		t.Fatal(err)
+	} else if cfg.opts.TLSConfig == nil || !cfg.opts.TLSConfig.InsecureSkipVerify {
+		t.Fatalf("unexpected TLS config: %+v", cfg.opts.TLSConfig)
+	}
+	if _, err = parseDsn("admin@admin:127.0.0.1/test?tls=unknown"); err == nil {
+		t.Fatal("expected error for unregistered TLS config")
+	}
+	if err = RegisterTLSConfig("true", conf); err == nil {
+		t.Fatal("expected error for reserved key")
+	}
+}
+
+func TestParseDsnOptions(t *testing.T) {
+	cfg, err := parseDsn("admin@admin:127.0.0.1/test?maxConnections=10&retryCount=-1&dialTimeout=5s&readTimeout=1m&maxIdleTime=5m&pingOnBorrow=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Options{MaxConnections: 10, RetryCount: -1, DialTimeout: 5 * time.Second, ReadTimeout: time.Minute,
+		MaxIdleTime: 5 * time.Minute, PingOnBorrow: true}
+	if cfg.opts != exp {
+		t.Fatalf("unexpected options: %+v", cfg.opts)
+	}
+	if _, err = parseDsn("admin@admin:127.0.0.1/test?readTimeout=abc"); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+}
+
+func TestClientRetryCount(t *testing.T) {
+	defer SetRetryCountConcurrent(0)
+	SetRetryCountConcurrent(3)
+	cases := []struct {
+		opt, exp int
+	}{
+		{0, 3},
+		{7, 7},
+		{-5, -1},
+	}
+	for _, c := range cases {
+		cli := &Client{opts: Options{RetryCount: c.opt}}
+		if n := cli.retryCount(); n != c.exp {
+			t.Fatalf("retry count for %d: expected %d, got %d", c.opt, c.exp, n)
+		}
+	}
+}
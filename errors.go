@@ -3,6 +3,8 @@ package orient
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"net"
 )
 
 var (
@@ -29,11 +31,20 @@ type Exception interface {
 	ExcMessage() string
 }
 
+// StackException is an optional interface of exceptions that carry a serialized Java exception sent by the server.
+// It's implemented by UnknownException, and is available on typed errors through their Exception field.
+type StackException interface {
+	Exception
+	// Returns serialized Java exception, including the stack trace, or nil if server did not send it
+	ExcStack() []byte
+}
+
 // UnknownException is an arbitrary exception from Java side.
 // If exception class is not recognized by this driver, it will return UnknownException.
 type UnknownException struct {
 	Class   string
 	Message string
+	Stack   []byte // Java-serialized exception; shared by all exceptions of one server response
 }
 
 // ExcClass returns Java exception class
@@ -45,6 +56,11 @@ func (e UnknownException) ExcClass() string {
 func (e UnknownException) ExcMessage() string {
 	return e.Message
 }
+
+// ExcStack returns Java-serialized exception
+func (e UnknownException) ExcStack() []byte {
+	return e.Stack
+}
 func (e UnknownException) Error() string {
 	return e.Class + ": " + e.Message
 }
@@ -67,6 +83,16 @@ func (e OServerException) Error() string {
 	return buf.String()
 }
 
+// Unwrap returns typed errors for all exceptions returned by the server (see ErrRecordNotFound, ErrValidation etc.),
+// so they can be matched with errors.Is and errors.As. Exceptions that are not recognized are returned as is.
+func (e OServerException) Unwrap() []error {
+	errs := make([]error, 0, len(e.Exceptions))
+	for _, ex := range e.Exceptions {
+		errs = append(errs, typedError(ex))
+	}
+	return errs
+}
+
 // ErrInvalidConn is returned than DB functions are called without active DB connection
 type ErrInvalidConn struct {
 	Msg string
@@ -76,6 +102,28 @@ func (e ErrInvalidConn) Error() string {
 	return "Invalid Connection: %s" + e.Msg
 }
 
+// ErrClosedConnection is returned by protocol implementations when connection to the server is lost.
+var ErrClosedConnection = fmt.Errorf("closed connection")
+
+// ErrClosedDatabase is returned when database is used after Close.
+var ErrClosedDatabase = fmt.Errorf("database is closed")
+
+// ErrReadTimeout is returned by protocol implementations when server does not respond to a request within
+// Options.ReadTimeout. Connection is closed in this case, since the response may never arrive.
+var ErrReadTimeout = fmt.Errorf("read timeout")
+
+// isConnError checks if error indicates that connection to the server is broken.
+func isConnError(err error) bool {
+	switch err {
+	case nil:
+		return false
+	case ErrClosedConnection, ErrReadTimeout, io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
 // ErrNoRecord is returned when trying to deserialize an empty result set into a single value.
 var ErrNoRecord = fmt.Errorf("no records returned, while expecting one")
 
@@ -89,6 +137,15 @@ func (e ErrMultipleRecords) Error() string {
 	return fmt.Sprintf("multiple records returned (%d), while expecting one: %s", e.N, e.Err)
 }
 
+// ErrMissingRecords is returned by Database.LoadRecords if some of the requested records do not exist.
+type ErrMissingRecords struct {
+	RIDs []RID
+}
+
+func (e ErrMissingRecords) Error() string {
+	return fmt.Sprintf("records do not exist: %v", e.RIDs)
+}
+
 func convertError(err error) error {
 	if err == nil {
 		return nil
@@ -110,3 +167,9 @@ type ErrConcurrentModification struct {
 func (e ErrConcurrentModification) Error() string {
 	return fmt.Sprintf("concurrent modification: %v", e.Exception)
 }
+
+// Is allows to check for this error with errors.Is(err, ErrConcurrentModification{}).
+func (e ErrConcurrentModification) Is(target error) bool {
+	_, ok := target.(ErrConcurrentModification)
+	return ok
+}
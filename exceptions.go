@@ -0,0 +1,256 @@
+package orient
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Typed errors for common server exceptions. All of them embed the original Exception, so the server message
+// and the serialized Java stack (see StackException) are still available. They can be matched with errors.As, or with errors.Is
+// using a zero value of the error type:
+//
+//	if errors.Is(err, orient.ErrRecordNotFound{}) { ... }
+//
+//	var verr orient.ErrValidation
+//	if errors.As(err, &verr) {
+//		log.Println(verr.Field, verr.Constraint)
+//	}
+//
+// Server exceptions are recognized by a simple class name, so the same error is returned regardless of the
+// package the exception class was moved to in a particular OrientDB version.
+
+// ErrRecordNotFound is returned when requested record does not exist.
+type ErrRecordNotFound struct {
+	Exception
+	RID RID // RID of the record; empty if it was not reported by the server
+}
+
+func (e ErrRecordNotFound) Error() string {
+	return fmt.Sprintf("record not found: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrRecordNotFound{}).
+func (e ErrRecordNotFound) Is(target error) bool {
+	_, ok := target.(ErrRecordNotFound)
+	return ok
+}
+
+// List of schema constraints reported by ErrValidation. Names match property attributes of ALTER PROPERTY.
+const (
+	ConstraintMandatory = "MANDATORY"
+	ConstraintNotNull   = "NOTNULL"
+	ConstraintMin       = "MIN"
+	ConstraintMax       = "MAX"
+	ConstraintRegexp    = "REGEXP"
+	ConstraintType      = "TYPE"
+	ConstraintReadonly  = "READONLY"
+)
+
+// ErrValidation is returned when a record does not pass schema validation.
+type ErrValidation struct {
+	Exception
+	Field      string // field name, as reported by the server (usually in a form of Class.field)
+	Constraint string // violated constraint (see ConstraintMandatory, etc); empty if not recognized
+}
+
+func (e ErrValidation) Error() string {
+	return fmt.Sprintf("validation failed: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrValidation{}).
+func (e ErrValidation) Is(target error) bool {
+	_, ok := target.(ErrValidation)
+	return ok
+}
+
+// ErrSecurity is returned when authentication fails or user has no permissions for an operation.
+type ErrSecurity struct {
+	Exception
+}
+
+func (e ErrSecurity) Error() string {
+	return fmt.Sprintf("security error: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrSecurity{}).
+func (e ErrSecurity) Is(target error) bool {
+	_, ok := target.(ErrSecurity)
+	return ok
+}
+
+// ErrCommandSyntax is returned when server cannot parse a command or a query.
+type ErrCommandSyntax struct {
+	Exception
+	Pos int // position of the error in a command text; -1 if it was not reported by the server
+}
+
+func (e ErrCommandSyntax) Error() string {
+	return fmt.Sprintf("syntax error: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrCommandSyntax{}).
+func (e ErrCommandSyntax) Is(target error) bool {
+	_, ok := target.(ErrCommandSyntax)
+	return ok
+}
+
+// ErrDatabaseNotFound is returned when opening a database that does not exist.
+type ErrDatabaseNotFound struct {
+	Exception
+	Name string // database name; empty if it was not reported by the server
+}
+
+func (e ErrDatabaseNotFound) Error() string {
+	return fmt.Sprintf("database not found: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrDatabaseNotFound{}).
+func (e ErrDatabaseNotFound) Is(target error) bool {
+	_, ok := target.(ErrDatabaseNotFound)
+	return ok
+}
+
+// ErrTimeout is returned when an operation was not completed by the server in time.
+type ErrTimeout struct {
+	Exception
+}
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("timeout: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrTimeout{}).
+func (e ErrTimeout) Is(target error) bool {
+	_, ok := target.(ErrTimeout)
+	return ok
+}
+
+// ErrOfflineNode is returned by a distributed cluster node that is not online yet (or anymore).
+type ErrOfflineNode struct {
+	Exception
+}
+
+func (e ErrOfflineNode) Error() string {
+	return fmt.Sprintf("node is offline: %v", e.Exception)
+}
+
+// Is allows to check for this error with errors.Is(err, ErrOfflineNode{}).
+func (e ErrOfflineNode) Is(target error) bool {
+	_, ok := target.(ErrOfflineNode)
+	return ok
+}
+
+var (
+	reExcRID      = regexp.MustCompile(`#-?\d+:-?\d+`)
+	reExcQuoted   = regexp.MustCompile(`'([^']+)'`)
+	reExcPosition = regexp.MustCompile(`position #?(\d+)`)
+	reExcColumn   = regexp.MustCompile(`line 1, column (\d+)`)
+)
+
+// typedErrors maps simple names of Java exception classes to functions that convert them to typed errors.
+// Functions may return nil if exception is not recognized.
+var typedErrors = map[string]func(e Exception) error{
+	"OConcurrentModificationException": func(e Exception) error { return ErrConcurrentModification{e} },
+	"ORecordNotFoundException":         newErrRecordNotFound,
+	"OValidationException":             newErrValidation,
+	"OSecurityException":               func(e Exception) error { return ErrSecurity{e} },
+	"OSecurityAccessException":         func(e Exception) error { return ErrSecurity{e} },
+	"OTokenSecurityException":          func(e Exception) error { return ErrSecurity{e} },
+	"OCommandSQLParsingException":      newErrCommandSyntax,
+	"OQueryParsingException":           newErrCommandSyntax,
+	"OStorageDoesNotExistException":    newErrDatabaseNotFound,
+	"OStorageException":                newErrDatabaseNotExists,
+	"ODatabaseException":               newErrDatabaseNotExists,
+	"OTimeoutException":                func(e Exception) error { return ErrTimeout{e} },
+	"OOfflineNodeException":            func(e Exception) error { return ErrOfflineNode{e} },
+}
+
+// typedError converts server exception to one of typed errors. Exception is returned as is if it is not recognized.
+func typedError(e Exception) error {
+	class := e.ExcClass()
+	if i := strings.LastIndexByte(class, '.'); i >= 0 {
+		class = class[i+1:]
+	}
+	if fnc, ok := typedErrors[class]; ok {
+		if err := fnc(e); err != nil {
+			return err
+		}
+	}
+	return e
+}
+
+func newErrRecordNotFound(e Exception) error {
+	err := ErrRecordNotFound{Exception: e, RID: NewEmptyRID()}
+	if s := reExcRID.FindString(e.ExcMessage()); s != "" {
+		if rid, perr := ParseRID(s); perr == nil {
+			err.RID = rid
+		}
+	}
+	return err
+}
+
+// validationConstraints maps parts of OValidationException messages to violated constraints.
+var validationConstraints = []struct {
+	text       string
+	constraint string
+}{
+	{"is mandatory", ConstraintMandatory},
+	{"cannot be null", ConstraintNotNull},
+	{"regular expression", ConstraintRegexp},
+	{"declared as", ConstraintType},
+	{"immutable", ConstraintReadonly},
+	{"contains fewer", ConstraintMin},
+	{"less than", ConstraintMin},
+	{"contains more", ConstraintMax},
+	{"greater than", ConstraintMax},
+}
+
+func newErrValidation(e Exception) error {
+	msg := e.ExcMessage()
+	err := ErrValidation{Exception: e}
+	if m := reExcQuoted.FindStringSubmatch(msg); m != nil {
+		err.Field = m[1]
+	}
+	for _, c := range validationConstraints {
+		if strings.Contains(msg, c.text) {
+			err.Constraint = c.constraint
+			break
+		}
+	}
+	return err
+}
+
+func newErrCommandSyntax(e Exception) error {
+	msg := e.ExcMessage()
+	err := ErrCommandSyntax{Exception: e, Pos: -1}
+	if m := reExcPosition.FindStringSubmatch(msg); m != nil {
+		err.Pos, _ = strconv.Atoi(m[1])
+	} else if m = reExcColumn.FindStringSubmatch(msg); m != nil {
+		col, _ := strconv.Atoi(m[1])
+		err.Pos = col - 1 // columns are counted from 1
+	}
+	return err
+}
+
+func newErrDatabaseNotFound(e Exception) error {
+	err := ErrDatabaseNotFound{Exception: e}
+	if m := reExcQuoted.FindStringSubmatch(e.ExcMessage()); m != nil {
+		name := m[1]
+		if i := strings.LastIndexAny(name, `/\`); i >= 0 { // storage path
+			name = name[i+1:]
+		}
+		err.Name = name
+	}
+	return err
+}
+
+// newErrDatabaseNotExists converts generic storage exceptions that are returned for missing databases.
+func newErrDatabaseNotExists(e Exception) error {
+	msg := strings.ToLower(e.ExcMessage())
+	if strings.Contains(msg, "not exist") || strings.Contains(msg, "not found") {
+		return newErrDatabaseNotFound(e)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package orient
+
+import (
+	"errors"
+	"testing"
+)
+
+func serverErr(class, msg string) error {
+	return OServerException{Exceptions: []Exception{
+		UnknownException{Class: "com.orientechnologies.orient.core.exception.OCommandExecutionException", Message: "Error on execution of command"},
+		UnknownException{Class: class, Message: msg, Stack: []byte("stack")},
+	}}
+}
+
+func TestTypedErrors(t *testing.T) {
+	var (
+		rerr ErrRecordNotFound
+		verr ErrValidation
+		serr ErrCommandSyntax
+		derr ErrDatabaseNotFound
+	)
+	err := serverErr("com.orientechnologies.orient.core.exception.ORecordNotFoundException", "The record with id '#12:34' was not found")
+	if !errors.As(err, &rerr) {
+		t.Fatalf("not a record not found error: %v", err)
+	} else if rerr.RID != NewRID(12, 34) {
+		t.Fatalf("unexpected rid: %v", rerr.RID)
+	} else if st, ok := rerr.Exception.(StackException); !ok || string(st.ExcStack()) != "stack" {
+		t.Fatalf("stack was not preserved: %v", rerr.Exception)
+	}
+
+	err = serverErr("com.orientechnologies.orient.core.exception.OValidationException", "The field 'Person.name' is mandatory, but not found on record: Person{}")
+	if !errors.As(err, &verr) {
+		t.Fatalf("not a validation error: %v", err)
+	} else if verr.Field != "Person.name" || verr.Constraint != ConstraintMandatory {
+		t.Fatalf("unexpected validation error: %q %q", verr.Field, verr.Constraint)
+	}
+	err = serverErr("com.orientechnologies.orient.core.exception.OValidationException", "The field 'Person.tags' contains more items than 3 requested")
+	if !errors.As(err, &verr) || verr.Constraint != ConstraintMax {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	err = serverErr("com.orientechnologies.orient.core.sql.OCommandSQLParsingException", "Error on parsing command at position #7: Invalid keyword")
+	if !errors.As(err, &serr) || serr.Pos != 7 {
+		t.Fatalf("unexpected syntax error: %v", err)
+	}
+	err = serverErr("com.orientechnologies.orient.core.sql.OCommandSQLParsingException", `Encountered " <IDENTIFIER> "FORM "" at line 1, column 8.`)
+	if !errors.As(err, &serr) || serr.Pos != 7 {
+		t.Fatalf("unexpected syntax error: %v", err)
+	}
+
+	err = serverErr("com.orientechnologies.orient.core.exception.OStorageException", "Cannot open the storage 'plocal:/orientdb/databases/missing' because it does not exist in path")
+	if !errors.As(err, &derr) || derr.Name != "missing" {
+		t.Fatalf("unexpected database error: %v", err)
+	}
+	if err = serverErr("com.orientechnologies.orient.core.exception.OStorageException", "Cannot lock storage"); errors.Is(err, ErrDatabaseNotFound{}) {
+		t.Fatal("generic storage error recognized as missing database")
+	}
+
+	for _, c := range []struct {
+		class  string
+		target error
+	}{
+		{"com.orientechnologies.orient.core.metadata.security.OSecurityAccessException", ErrSecurity{}},
+		{"com.orientechnologies.common.concur.OTimeoutException", ErrTimeout{}},
+		{"com.orientechnologies.orient.core.exception.OOfflineNodeException", ErrOfflineNode{}},
+		{"com.orientechnologies.orient.core.exception.OConcurrentModificationException", ErrConcurrentModification{}},
+	} {
+		if err = serverErr(c.class, "msg"); !errors.Is(err, c.target) {
+			t.Fatalf("%s: expected %T", c.class, c.target)
+		} else if errors.Is(err, ErrRecordNotFound{}) {
+			t.Fatalf("%s: unexpected match", c.class)
+		}
+	}
+	var uerr UnknownException
+	if err = serverErr("org.foo.BlargException", "msg"); !errors.As(err, &uerr) || uerr.Class != "com.orientechnologies.orient.core.exception.OCommandExecutionException" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package orient
 
 import (
+	"context"
 	"fmt"
 	"github.com/nu7hatch/gouuid"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
@@ -36,6 +37,79 @@ type RidBag struct {
 func (bag *RidBag) SetOwner(doc *Document) {
 	bag.owner = doc
 }
+
+// Add adds a link to the bag. For tree-based bags, the change is sent to the server when owning document is saved.
+func (bag *RidBag) Add(id OIdentifiable) {
+	bag.delegate.add(id)
+}
+
+// Remove removes one occurrence of the link from the bag. For tree-based bags, the change is sent to the server
+// when owning document is saved.
+func (bag *RidBag) Remove(id OIdentifiable) {
+	bag.delegate.remove(id)
+}
+
+// Size returns the number of links in the bag. For tree-based bags the size is requested from the server,
+// taking into account unsaved changes.
+func (bag *RidBag) Size(db *Database) (int, error) {
+	return bag.SizeContext(context.Background(), db)
+}
+
+// SizeContext is like Size, but allows to cancel the request with a context.
+func (bag *RidBag) SizeContext(ctx context.Context, db *Database) (int, error) {
+	switch d := bag.delegate.(type) {
+	case *embeddedRidBag:
+		return len(d.links), nil
+	case *sbTreeRidBag:
+		if d.ptr == nil { // not saved yet
+			n := 0
+			for _, ch := range d.changes {
+				n += ch.apply(0)
+			}
+			return n, nil
+		} else if db == nil {
+			return 0, fmt.Errorf("database is required for tree-based RidBag")
+		}
+		return db.RidBagSizeContext(ctx, *d.ptr, d.changes)
+	}
+	return 0, fmt.Errorf("unsupported RidBag type: %T", bag.delegate)
+}
+
+// Iterator returns an iterator over links in the bag. Links of tree-based bags are loaded from the server
+// page by page, so the database must be set for them. Unsaved changes are taken into account.
+func (bag *RidBag) Iterator(db *Database) *RidBagIterator {
+	it := &RidBagIterator{db: db}
+	switch d := bag.delegate.(type) {
+	case *embeddedRidBag:
+		it.links = append(it.links, d.links...)
+		it.done = true
+	case *sbTreeRidBag:
+		it.ptr = d.ptr
+		it.changes = d.changes
+		it.seen = make(map[RID]struct{})
+		it.done = d.ptr == nil
+		if it.done {
+			it.addNew()
+		}
+	default:
+		it.err = fmt.Errorf("unsupported RidBag type: %T", bag.delegate)
+	}
+	return it
+}
+
+// saved is called when the owning document is saved, so pending changes are persisted on the server.
+func (bag *RidBag) saved() {
+	if d, ok := bag.delegate.(*sbTreeRidBag); ok {
+		d.changes = make(RidBagChanges)
+	}
+}
+
+// setPointer is called when the server creates or moves the tree of a tree-based bag.
+func (bag *RidBag) setPointer(ptr BonsaiPointer) {
+	if d, ok := bag.delegate.(*sbTreeRidBag); ok {
+		d.ptr = &ptr
+	}
+}
 func (bag *RidBag) FromStream(r io.Reader) error {
 	br := rw.NewReader(r)
 	first := br.ReadByte()
@@ -57,9 +131,15 @@ func (bag *RidBag) FromStream(r io.Reader) error {
 }
 func (bag *RidBag) ToStream(w io.Writer) error {
 	var first byte
-	hasUUID := false // TODO: do we need to send it?
+	hasUUID := bag.IsRemote() // server reports new pointers of tree-based bags by UUID
 	if !bag.IsRemote() {
 		first |= 0x1
+	} else if bag.id == (uuid.UUID{}) {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		bag.id = *id
 	}
 	if hasUUID {
 		first |= 0x2
@@ -83,6 +163,8 @@ func (bag *RidBag) IsRemote() bool {
 type ridBagDelegate interface {
 	deserializeDelegate(br *rw.Reader) error
 	serializeDelegate(bw *rw.Writer) error
+	add(id OIdentifiable)
+	remove(id OIdentifiable)
 }
 
 func newEmbeddedRidBag() ridBagDelegate { return &embeddedRidBag{} }
@@ -103,6 +185,18 @@ func (bag *embeddedRidBag) deserializeDelegate(br *rw.Reader) error {
 	}
 	return br.Err()
 }
+func (bag *embeddedRidBag) add(id OIdentifiable) {
+	bag.links = append(bag.links, id)
+}
+func (bag *embeddedRidBag) remove(id OIdentifiable) {
+	rid := id.GetIdentity()
+	for i, l := range bag.links {
+		if l.GetIdentity() == rid {
+			bag.links = append(bag.links[:i], bag.links[i+1:]...)
+			return
+		}
+	}
+}
 func (bag *embeddedRidBag) serializeDelegate(bw *rw.Writer) error {
 	bw.WriteInt(int32(len(bag.links)))
 	for _, l := range bag.links {
@@ -113,39 +207,102 @@ func (bag *embeddedRidBag) serializeDelegate(bw *rw.Writer) error {
 	return bw.Err()
 }
 
-func newSBTreeRidBag() ridBagDelegate { return &sbTreeRidBag{} }
-func newBonsaiCollectionPtr(fileId int64, pageIndex int64, pageOffset int) *bonsaiCollectionPtr {
-	return &bonsaiCollectionPtr{
-		fileId:     fileId,
-		pageIndex:  pageIndex,
-		pageOffset: pageOffset,
+func newSBTreeRidBag() ridBagDelegate { return &sbTreeRidBag{changes: make(RidBagChanges)} }
+
+// BonsaiPointer points to a tree (OSBTreeBonsai) that holds the content of tree-based RidBag on the server.
+type BonsaiPointer struct {
+	FileID     int64
+	PageIndex  int64
+	PageOffset int
+}
+
+// ToStream writes the pointer in a form of (fileId:long)(pageIndex:long)(pageOffset:int).
+func (p BonsaiPointer) ToStream(w io.Writer) error {
+	bw := rw.NewWriter(w)
+	bw.WriteLong(p.FileID)
+	bw.WriteLong(p.PageIndex)
+	bw.WriteInt(int32(p.PageOffset))
+	return bw.Err()
+}
+
+// RidBagEntry is a link stored in tree-based RidBag, with a number of its occurrences.
+type RidBagEntry struct {
+	RID   RID
+	Count int
+}
+
+// RidBagChange is a pending change of the link counter in tree-based RidBag.
+type RidBagChange struct {
+	Value    int
+	Absolute bool // Value replaces the counter instead of being added to it
+}
+
+func (ch RidBagChange) apply(n int) int {
+	if ch.Absolute {
+		n = ch.Value
+	} else {
+		n += ch.Value
 	}
+	if n < 0 {
+		n = 0
+	}
+	return n
 }
 
-type bonsaiCollectionPtr struct {
-	fileId     int64
-	pageIndex  int64
-	pageOffset int
+// RidBagChanges is a set of pending changes of tree-based RidBag.
+type RidBagChanges map[RID]RidBagChange
+
+// ToStream writes changes in a form of (count:int)[(link:rid)(value:int)(type:byte)]*
+func (changes RidBagChanges) ToStream(w io.Writer) error {
+	bw := rw.NewWriter(w)
+	bw.WriteInt(int32(len(changes)))
+	for rid, ch := range changes {
+		if err := rid.ToStream(bw); err != nil {
+			return err
+		}
+		bw.WriteInt(int32(ch.Value))
+		if ch.Absolute {
+			bw.WriteByte(1)
+		} else {
+			bw.WriteByte(0)
+		}
+	}
+	return bw.Err()
 }
+
 type sbTreeRidBag struct {
-	collectionPtr *bonsaiCollectionPtr
-	changes       map[RID][]interface{}
-	size          int
+	ptr     *BonsaiPointer // nil if tree is not created yet
+	changes RidBagChanges
 }
 
+func (bag *sbTreeRidBag) add(id OIdentifiable) {
+	rid := id.GetIdentity()
+	ch := bag.changes[rid]
+	ch.Value++
+	bag.changes[rid] = ch
+}
+func (bag *sbTreeRidBag) remove(id OIdentifiable) {
+	rid := id.GetIdentity()
+	ch := bag.changes[rid]
+	if ch.Absolute && ch.Value == 0 {
+		return
+	}
+	ch.Value--
+	bag.changes[rid] = ch
+}
 func (bag *sbTreeRidBag) serializeDelegate(bw *rw.Writer) error {
-	if bag.collectionPtr == nil {
+	if bag.ptr == nil {
 		bw.WriteLong(-1)
 		bw.WriteLong(-1)
 		bw.WriteInt(-1)
-	} else {
-		bw.WriteLong(bag.collectionPtr.fileId)
-		bw.WriteLong(bag.collectionPtr.pageIndex)
-		bw.WriteInt(int32(bag.collectionPtr.pageOffset))
+	} else if err := bag.ptr.ToStream(bw); err != nil {
+		return err
 	}
 	bw.WriteInt(-1) // TODO: cached size; need a real value for compatibility with <= 1.7.5
-	bw.WriteInt(0)  // TODO: support changes in sbTreeRidBag
-	return bw.Err()
+	if err := bw.Err(); err != nil {
+		return err
+	}
+	return bag.changes.ToStream(bw)
 }
 func (bag *sbTreeRidBag) deserializeDelegate(br *rw.Reader) error {
 	fileId := br.ReadLong()
@@ -156,22 +313,15 @@ func (bag *sbTreeRidBag) deserializeDelegate(br *rw.Reader) error {
 		return err
 	}
 	if fileId == -1 {
-		bag.collectionPtr = nil
+		bag.ptr = nil
 	} else {
-		bag.collectionPtr = newBonsaiCollectionPtr(fileId, pageIndex, pageOffset)
+		bag.ptr = &BonsaiPointer{FileID: fileId, PageIndex: pageIndex, PageOffset: pageOffset}
 	}
-	bag.size = -1
 	return bag.deserializeChanges(br)
 }
 func (bag *sbTreeRidBag) deserializeChanges(r *rw.Reader) (err error) {
 	n := int(r.ReadInt())
-	changes := make(map[RID][]interface{})
-
-	type change struct {
-		diff bool
-		val  int
-	}
-
+	changes := make(RidBagChanges, n)
 	for i := 0; i < n; i++ {
 		var rid RID
 		if err = rid.FromStream(r); err != nil {
@@ -179,17 +329,115 @@ func (bag *sbTreeRidBag) deserializeChanges(r *rw.Reader) (err error) {
 		}
 		chval := int(r.ReadInt())
 		chtp := int(r.ReadByte())
-		arr := changes[rid]
 		switch chtp {
 		case 1: // abs
-			arr = append(arr, change{diff: false, val: chval})
+			changes[rid] = RidBagChange{Value: chval, Absolute: true}
 		case 0: // diff
-			arr = append(arr, change{diff: true, val: chval})
+			ch := changes[rid]
+			ch.Value += chval
+			changes[rid] = ch
 		default:
 			return fmt.Errorf("unknown change type: %d", chtp)
 		}
-		changes[rid] = arr
 	}
 	bag.changes = changes
 	return r.Err()
 }
+
+// ridBagPageSize is a number of entries of tree-based RidBag requested at once.
+const ridBagPageSize = 128
+
+// RidBagIterator iterates over links in RidBag. Each link is returned as many times as it was added to the bag.
+type RidBagIterator struct {
+	db      *Database
+	ptr     *BonsaiPointer
+	changes RidBagChanges
+	seen    map[RID]struct{} // links with pending changes that were already returned
+
+	links []OIdentifiable // current page
+	last  *RID            // last key of the previous page
+	cur   OIdentifiable
+	done  bool // no more pages on the server
+	err   error
+}
+
+// Next advances iterator to the next link. It returns false at the end or on error (see Err).
+func (it *RidBagIterator) Next() bool {
+	return it.NextContext(context.Background())
+}
+
+// NextContext is like Next, but allows to cancel the request with a context.
+func (it *RidBagIterator) NextContext(ctx context.Context) bool {
+	for len(it.links) == 0 {
+		if it.done || it.err != nil {
+			it.cur = nil
+			return false
+		}
+		it.err = it.fetch(ctx)
+	}
+	it.cur, it.links = it.links[0], it.links[1:]
+	return true
+}
+
+// Link returns current link.
+func (it *RidBagIterator) Link() OIdentifiable {
+	return it.cur
+}
+
+// Err returns an error that occurred during iteration.
+func (it *RidBagIterator) Err() error {
+	return it.err
+}
+
+// fetch loads next page of entries from the server.
+func (it *RidBagIterator) fetch(ctx context.Context) error {
+	if it.db == nil {
+		return fmt.Errorf("database is required for tree-based RidBag")
+	}
+	var (
+		entries []RidBagEntry
+		err     error
+	)
+	if it.last == nil {
+		var first RID
+		first, err = it.db.RidBagFirstKeyContext(ctx, *it.ptr)
+		if err == nil && first.IsValid() {
+			entries, err = it.db.RidBagEntriesMajorContext(ctx, *it.ptr, first, true, ridBagPageSize)
+		}
+	} else {
+		entries, err = it.db.RidBagEntriesMajorContext(ctx, *it.ptr, *it.last, false, ridBagPageSize)
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		it.done = true
+		it.addNew()
+		return nil
+	}
+	last := entries[len(entries)-1].RID
+	it.last = &last
+	for _, e := range entries {
+		n := e.Count
+		if ch, ok := it.changes[e.RID]; ok {
+			n = ch.apply(n)
+			it.seen[e.RID] = struct{}{}
+		}
+		for i := 0; i < n; i++ {
+			it.links = append(it.links, e.RID)
+		}
+	}
+	return nil
+}
+
+// addNew adds links that were added to the bag, but are not stored on the server yet.
+func (it *RidBagIterator) addNew() {
+	for rid, ch := range it.changes {
+		if _, ok := it.seen[rid]; ok {
+			continue
+		}
+		for i, n := 0, ch.apply(0); i < n; i++ {
+			it.links = append(it.links, rid)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package orient
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/nu7hatch/gouuid"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+// bagSession emulates a server-side tree of a single RidBag.
+type bagSession struct {
+	testSession
+	entries []RidBagEntry // sorted by RID
+	pages   int
+}
+
+func (s *bagSession) RidBagFirstKeyContext(ctx context.Context, ptr BonsaiPointer) (RID, error) {
+	if len(s.entries) == 0 {
+		return NewEmptyRID(), nil
+	}
+	return s.entries[0].RID, nil
+}
+
+func (s *bagSession) RidBagEntriesMajorContext(ctx context.Context, ptr BonsaiPointer, key RID, inclusive bool, pageSize int) ([]RidBagEntry, error) {
+	s.pages++
+	var out []RidBagEntry
+	for _, e := range s.entries {
+		if e.RID.ClusterPos > key.ClusterPos || (inclusive && e.RID == key) {
+			out = append(out, e)
+		}
+		if len(out) == pageSize {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (s *bagSession) RidBagSizeContext(ctx context.Context, ptr BonsaiPointer, changes RidBagChanges) (int, error) {
+	n := 0
+	for _, e := range s.entries {
+		c := e.Count
+		if ch, ok := changes[e.RID]; ok {
+			c = ch.apply(c)
+		}
+		n += c
+	}
+	for rid, ch := range changes {
+		if ch.Absolute || ch.Value > 0 {
+			found := false
+			for _, e := range s.entries {
+				found = found || e.RID == rid
+			}
+			if !found {
+				n += ch.apply(0)
+			}
+		}
+	}
+	return n, nil
+}
+
+func newTreeRidBag(t *testing.T) *RidBag {
+	buf := bytes.NewBuffer(nil)
+	w := rw.NewWriter(buf)
+	w.WriteByte(0) // tree-based, no uuid
+	BonsaiPointer{FileID: 1, PageIndex: 2, PageOffset: 3}.ToStream(w)
+	w.WriteInt(-1) // cached size
+	w.WriteInt(0)  // no changes
+	bag := NewRidBag()
+	if err := bag.FromStream(buf); err != nil {
+		t.Fatal(err)
+	} else if !bag.IsRemote() {
+		t.Fatal("expected tree-based bag")
+	}
+	return bag
+}
+
+func TestRidBagTree(t *testing.T) {
+	sess := &bagSession{}
+	for i := 0; i < ridBagPageSize+10; i++ {
+		sess.entries = append(sess.entries, RidBagEntry{RID: NewRID(5, int64(i)), Count: 1})
+	}
+	sess.entries[0].Count = 2
+	db := newTestDB(t, func() DBSession { return sess })
+
+	bag := newTreeRidBag(t)
+	bag.Add(NewRID(5, 1000))
+	bag.Remove(NewRID(5, 1))
+	bag.Remove(NewRID(5, 0))
+
+	var links []RID
+	it := bag.Iterator(db)
+	for it.Next() {
+		links = append(links, it.Link().GetIdentity())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	exp := len(sess.entries) + 1 + 1 - 2 // first link is stored twice, one link added and two removed
+	if len(links) != exp {
+		t.Fatalf("expected %d links, got %d", exp, len(links))
+	} else if links[0] != NewRID(5, 0) || links[1] != NewRID(5, 2) {
+		t.Fatalf("unexpected links: %v", links[:2])
+	} else if links[len(links)-1] != NewRID(5, 1000) {
+		t.Fatalf("added link is missing: %v", links[len(links)-1])
+	} else if sess.pages != 3 {
+		t.Fatalf("expected 3 pages to be requested, got %d", sess.pages)
+	}
+	if n, err := bag.Size(db); err != nil {
+		t.Fatal(err)
+	} else if n != exp {
+		t.Fatalf("expected size %d, got %d", exp, n)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := bag.ToStream(buf); err != nil {
+		t.Fatal(err)
+	}
+	bag2 := NewRidBag()
+	if err := bag2.FromStream(buf); err != nil {
+		t.Fatal(err)
+	}
+	changes := bag2.delegate.(*sbTreeRidBag).changes
+	if len(changes) != 3 || changes[NewRID(5, 1000)].Value != 1 || changes[NewRID(5, 1)].Value != -1 {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+	bag.saved()
+	if n, _ := bag.Size(db); n != len(sess.entries)+1 {
+		t.Fatalf("changes were not cleared: %d", n)
+	}
+}
+
+func TestRidBagSavedPointer(t *testing.T) {
+	bag := NewRidBag()
+	bag.delegate = newSBTreeRidBag() // tree is not created yet
+	bag.Add(NewRID(5, 1))
+	doc := NewDocument("V").SetField("out", bag)
+
+	buf := bytes.NewBuffer(nil)
+	if err := bag.ToStream(buf); err != nil {
+		t.Fatal(err)
+	}
+	bag2 := NewRidBag()
+	if err := bag2.FromStream(buf); err != nil {
+		t.Fatal(err)
+	} else if bag2.id != bag.id || bag.id == (uuid.UUID{}) {
+		t.Fatalf("uuid was not sent: %v vs %v", bag2.id, bag.id)
+	}
+
+	ptr := BonsaiPointer{FileID: 1, PageIndex: 2, PageOffset: 3}
+	doc.SetRidBagPointers(map[uuid.UUID]BonsaiPointer{bag.id: ptr})
+	bag.saved()
+	if d := bag.delegate.(*sbTreeRidBag); d.ptr == nil || *d.ptr != ptr {
+		t.Fatalf("pointer was not set: %v", d.ptr)
+	}
+	sess := &bagSession{entries: []RidBagEntry{{RID: NewRID(5, 1), Count: 1}}}
+	db := newTestDB(t, func() DBSession { return sess })
+	if n, err := bag.Size(db); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("expected size 1, got %d", n)
+	}
+}
+
+func TestRidBagEmbedded(t *testing.T) {
+	bag := NewRidBag()
+	bag.Add(NewRID(1, 1))
+	bag.Add(NewRID(1, 2))
+	bag.Add(NewRID(1, 1))
+	bag.Remove(NewRID(1, 1))
+	if n, err := bag.Size(nil); err != nil || n != 2 {
+		t.Fatalf("unexpected size: %d (%v)", n, err)
+	}
+	var links []OIdentifiable
+	for it := bag.Iterator(nil); it.Next(); {
+		links = append(links, it.Link())
+	}
+	if len(links) != 2 || links[0] != NewRID(1, 2) || links[1] != NewRID(1, 1) {
+		t.Fatalf("unexpected links: %v", links)
+	}
+}
@@ -0,0 +1,177 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LiveOperation is a type of record change, reported by live query.
+type LiveOperation byte
+
+// List of live query operations
+const (
+	LiveUpdated LiveOperation = 1
+	LiveDeleted LiveOperation = 2
+	LiveCreated LiveOperation = 3
+	// LiveUnsubscribed is sent by protocol implementation as the last event of a subscription.
+	// It is never delivered to LiveSubscription.Events.
+	LiveUnsubscribed LiveOperation = 'u'
+)
+
+func (op LiveOperation) String() string {
+	switch op {
+	case LiveUpdated:
+		return "update"
+	case LiveDeleted:
+		return "delete"
+	case LiveCreated:
+		return "create"
+	case LiveUnsubscribed:
+		return "unsubscribe"
+	}
+	return fmt.Sprintf("LiveOperation(%d)", byte(op))
+}
+
+// LiveEvent is a record change, reported by live query.
+type LiveEvent struct {
+	Op     LiveOperation
+	Record ORecord
+}
+
+// LiveSubscription is an active live query. It uses a dedicated connection to the server,
+// so it must be closed when it is no longer needed.
+type LiveSubscription struct {
+	token int
+	conn  DBSession
+
+	events chan LiveEvent
+	notify chan struct{}
+	done   chan struct{}
+
+	mu      sync.Mutex
+	queue   []LiveEvent
+	ended   bool // server ended the subscription
+	closing bool
+}
+
+// LiveQuery subscribes to changes of records, matching a given query. Query may be written either with
+// or without LIVE keyword:
+//
+//		sub, err := db.LiveQuery("LIVE SELECT FROM Order WHERE status = ?", "new")
+//		...
+//		defer sub.Close()
+//		for ev := range sub.Events() {
+//			fmt.Println(ev.Op, ev.Record)
+//		}
+//
+// Events channel is closed when subscription is closed, or if connection to the server is lost.
+func (db *Database) LiveQuery(sql string, params ...interface{}) (*LiveSubscription, error) {
+	return db.LiveQueryContext(context.Background(), sql, params...)
+}
+
+// LiveQueryContext is like LiveQuery, but allows to cancel the subscribe request with a context.
+func (db *Database) LiveQueryContext(ctx context.Context, sql string, params ...interface{}) (*LiveSubscription, error) {
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "LIVE ") {
+		sql = "LIVE " + sql
+	}
+	conn, err := db.pool.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &LiveSubscription{
+		conn:   conn,
+		events: make(chan LiveEvent),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	s.token, err = conn.LiveQueryContext(ctx, NewSQLQuery(sql, params...), s.push)
+	if err != nil {
+		conn.Close()
+		return nil, convertError(err)
+	}
+	go s.run()
+	return s, nil
+}
+
+// Token returns a server-side token of live query.
+func (s *LiveSubscription) Token() int { return s.token }
+
+// Events returns a channel with record changes. Events are buffered internally, thus slow consumer will not block
+// the connection, but it may lead to excessive memory usage.
+func (s *LiveSubscription) Events() <-chan LiveEvent { return s.events }
+
+// push adds an event to the queue. It is called from connection reading goroutine, thus it must not block.
+func (s *LiveSubscription) push(ev LiveEvent) {
+	s.mu.Lock()
+	if ev.Op == LiveUnsubscribed {
+		s.ended = true
+	} else if !s.ended {
+		s.queue = append(s.queue, ev)
+	}
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run forwards queued events to the channel.
+func (s *LiveSubscription) run() {
+	defer close(s.events)
+	for {
+		s.mu.Lock()
+		var ev LiveEvent
+		ok := len(s.queue) > 0
+		if ok {
+			ev = s.queue[0]
+			s.queue[0] = LiveEvent{}
+			s.queue = s.queue[1:]
+		}
+		ended := s.ended
+		s.mu.Unlock()
+		if !ok {
+			if ended {
+				return
+			}
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case s.events <- ev:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close unsubscribes from live query and closes the connection used by subscription.
+func (s *LiveSubscription) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but allows to cancel the unsubscribe request with a context.
+func (s *LiveSubscription) CloseContext(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closing = true
+	ended := s.ended
+	s.mu.Unlock()
+	var err error
+	if !ended {
+		err = convertError(s.conn.LiveUnsubscribeContext(ctx, s.token))
+	}
+	close(s.done)
+	if err1 := s.conn.Close(); err == nil && !ended {
+		err = err1
+	}
+	return err
+}
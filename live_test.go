@@ -0,0 +1,42 @@
+package orient
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSubscription() *LiveSubscription {
+	s := &LiveSubscription{
+		events: make(chan LiveEvent),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func TestLiveSubscriptionQueue(t *testing.T) {
+	s := newTestSubscription()
+	// events must not block, even if nobody reads them
+	for i := 1; i <= 3; i++ {
+		s.push(LiveEvent{Op: LiveCreated, Record: &BytesRecord{Vers: i}})
+	}
+	s.push(LiveEvent{Op: LiveUnsubscribed})
+
+	var got []int
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev, ok := <-s.Events():
+			if !ok {
+				if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+					t.Fatalf("unexpected events: %v", got)
+				}
+				return
+			}
+			got = append(got, ev.Record.Version())
+		case <-timeout:
+			t.Fatal("events channel was not closed")
+		}
+	}
+}
@@ -12,6 +12,7 @@ var TagName = "mapstructure"
 var mapDecoderHooks = []mapstructure.DecodeHookFunc{
 	stringToTimeHookFunc,
 	stringToByteSliceHookFunc,
+	fieldTagsHookFunc,
 	documentToMapHookFunc,
 }
 
@@ -0,0 +1,150 @@
+package orient
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DialStrategy defines how new connections are distributed between nodes of distributed OrientDB cluster.
+type DialStrategy int
+
+const (
+	// StrategySticky sends all connections to a single node and switches to the next one only if current node goes down.
+	StrategySticky DialStrategy = iota
+	// StrategyRoundRobin distributes new connections between all available nodes.
+	StrategyRoundRobin
+	// StrategyReadAnyWriteMaster sends writes to a single node (the first one in the list is the master),
+	// while queries and record reads are distributed between all available nodes.
+	StrategyReadAnyWriteMaster
+)
+
+func (s DialStrategy) String() string {
+	switch s {
+	case StrategySticky:
+		return "sticky"
+	case StrategyRoundRobin:
+		return "round-robin"
+	case StrategyReadAnyWriteMaster:
+		return "read-any-write-master"
+	}
+	return fmt.Sprintf("DialStrategy(%d)", int(s))
+}
+
+// nodeRetryDelay is a time for which a failed node will be skipped when choosing a node to dial.
+const nodeRetryDelay = time.Second * 5
+
+// ParseAddrs splits server address into a list of node addresses. Supported formats are:
+//
+//		host:port
+//		host1:port;host2:port;host3
+//		host1:port,host2:port
+//		remote:host1;host2;host3
+//
+// Empty address results in a list with one empty address, meaning the default one.
+func ParseAddrs(addr string) ([]string, error) {
+	addr = strings.TrimSpace(addr)
+	addr = strings.TrimPrefix(addr, "remote:")
+	if i := strings.Index(addr, "/"); i >= 0 {
+		if name := strings.Trim(addr[i:], "/"); name != "" {
+			return nil, fmt.Errorf("orientgo: database name %q is not allowed in server address, pass it to Open instead", name)
+		}
+		addr = addr[:i]
+	}
+	var addrs []string
+	for _, a := range strings.FieldsFunc(addr, func(r rune) bool { return r == ';' || r == ',' }) {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	if len(addrs) == 0 {
+		addrs = []string{""}
+	}
+	return addrs, nil
+}
+
+type node struct {
+	addr   string
+	failed time.Time // time of the last failed dial
+}
+
+// nodeList chooses a node for each new connection according to dial strategy.
+type nodeList struct {
+	strategy DialStrategy
+
+	mu    sync.Mutex
+	nodes []node
+	cur   int // current node for sticky connections
+	next  int // next node for round-robin connections
+}
+
+func newNodeList(addrs []string, strategy DialStrategy) *nodeList {
+	l := &nodeList{strategy: strategy, nodes: make([]node, len(addrs))}
+	for i, a := range addrs {
+		l.nodes[i].addr = a
+	}
+	return l
+}
+
+// roundRobin checks if connection should be distributed between all nodes.
+func (l *nodeList) roundRobin(read bool) bool {
+	return l.strategy == StrategyRoundRobin || (read && l.strategy == StrategyReadAnyWriteMaster)
+}
+
+// order returns node indexes in the order they should be tried.
+// Nodes that failed recently are moved to the end of the list.
+func (l *nodeList) order(read bool) []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.nodes)
+	start := l.cur
+	if l.roundRobin(read) {
+		start = l.next
+		l.next = (l.next + 1) % n
+	}
+	now := time.Now()
+	alive := make([]int, 0, n)
+	var down []int
+	for i := 0; i < n; i++ {
+		j := (start + i) % n
+		if f := l.nodes[j].failed; !f.IsZero() && now.Sub(f) < nodeRetryDelay {
+			down = append(down, j)
+		} else {
+			alive = append(alive, j)
+		}
+	}
+	return append(alive, down...)
+}
+
+func (l *nodeList) markFailed(i int) {
+	l.mu.Lock()
+	l.nodes[i].failed = time.Now()
+	l.mu.Unlock()
+}
+
+func (l *nodeList) markAlive(i int, read bool) {
+	l.mu.Lock()
+	l.nodes[i].failed = time.Time{}
+	if !l.roundRobin(read) {
+		l.cur = i
+	}
+	l.mu.Unlock()
+}
+
+// dial connects to the first healthy node according to dial strategy. Nodes are tried in order until one
+// of them accepts the connection. Error of the last dial is returned if all nodes are unreachable.
+func (l *nodeList) dial(read bool, dial func(addr string) (DBConnection, error)) (DBConnection, error) {
+	var last error
+	for _, i := range l.order(read) {
+		conn, err := dial(l.nodes[i].addr)
+		if err != nil {
+			l.markFailed(i)
+			last = err
+			continue
+		}
+		l.markAlive(i, read)
+		return conn, nil
+	}
+	return nil, last
+}
@@ -0,0 +1,112 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseAddrs(t *testing.T) {
+	cases := []struct {
+		addr string
+		exp  []string
+	}{
+		{"", []string{""}},
+		{"localhost:2424", []string{"localhost:2424"}},
+		{"h1:2424;h2:2425;h3", []string{"h1:2424", "h2:2425", "h3"}},
+		{"h1, h2", []string{"h1", "h2"}},
+		{"remote:h1;h2;h3", []string{"h1", "h2", "h3"}},
+		{"remote:h1;h2/", []string{"h1", "h2"}},
+	}
+	for _, c := range cases {
+		addrs, err := ParseAddrs(c.addr)
+		if err != nil {
+			t.Fatalf("%q: %v", c.addr, err)
+		} else if !reflect.DeepEqual(addrs, c.exp) {
+			t.Fatalf("%q: expected %q, got %q", c.addr, c.exp, addrs)
+		}
+	}
+	if _, err := ParseAddrs("remote:h1;h2/db"); err == nil {
+		t.Fatal("expected error for address with database name")
+	}
+}
+
+type fakeConn struct {
+	addr string
+}
+
+func (c fakeConn) AuthContext(ctx context.Context, user, pass string) (DBAdmin, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (c fakeConn) OpenContext(ctx context.Context, name string, dbType DatabaseType, user, pass string) (DBSession, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (c fakeConn) OpenTokenContext(ctx context.Context, name string, dbType DatabaseType, tok SessionToken) (DBSession, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (c fakeConn) Close() error { return nil }
+
+type fakeCluster map[string]bool // addr -> is down
+
+func (fc fakeCluster) dial(addr string) (DBConnection, error) {
+	if fc[addr] {
+		return nil, fmt.Errorf("node %s is down", addr)
+	}
+	return fakeConn{addr: addr}, nil
+}
+
+func dialAddrs(t *testing.T, l *nodeList, fc fakeCluster, read bool, n int) []string {
+	var out []string
+	for i := 0; i < n; i++ {
+		conn, err := l.dial(read, fc.dial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, conn.(fakeConn).addr)
+	}
+	return out
+}
+
+func TestNodesSticky(t *testing.T) {
+	fc := fakeCluster{}
+	l := newNodeList([]string{"a", "b", "c"}, StrategySticky)
+	if got := dialAddrs(t, l, fc, false, 2); !reflect.DeepEqual(got, []string{"a", "a"}) {
+		t.Fatalf("unexpected nodes: %q", got)
+	}
+	fc["a"] = true
+	if got := dialAddrs(t, l, fc, false, 2); !reflect.DeepEqual(got, []string{"b", "b"}) {
+		t.Fatalf("unexpected nodes after failover: %q", got)
+	}
+	fc["a"] = false // node is back, but we should stick with current one
+	if got := dialAddrs(t, l, fc, false, 1); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("unexpected nodes after recovery: %q", got)
+	}
+}
+
+func TestNodesRoundRobin(t *testing.T) {
+	fc := fakeCluster{"b": true}
+	l := newNodeList([]string{"a", "b", "c"}, StrategyRoundRobin)
+	if got := dialAddrs(t, l, fc, false, 4); !reflect.DeepEqual(got, []string{"a", "c", "c", "a"}) {
+		t.Fatalf("unexpected nodes: %q", got)
+	}
+}
+
+func TestNodesReadAnyWriteMaster(t *testing.T) {
+	fc := fakeCluster{}
+	l := newNodeList([]string{"a", "b", "c"}, StrategyReadAnyWriteMaster)
+	if got := dialAddrs(t, l, fc, true, 3); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected read nodes: %q", got)
+	}
+	if got := dialAddrs(t, l, fc, false, 2); !reflect.DeepEqual(got, []string{"a", "a"}) {
+		t.Fatalf("unexpected write nodes: %q", got)
+	}
+}
+
+func TestNodesAllDown(t *testing.T) {
+	fc := fakeCluster{"a": true, "b": true}
+	l := newNodeList([]string{"a", "b"}, StrategySticky)
+	if _, err := l.dial(false, fc.dial); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -2,6 +2,8 @@ package obinary
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
@@ -14,8 +16,8 @@ import (
 )
 
 func init() {
-	orient.RegisterProto(orient.ProtoBinary, func(addr string) (orient.DBConnection, error) {
-		return Dial(addr)
+	orient.RegisterProto(orient.ProtoBinary, func(addr string, opts orient.Options) (orient.DBConnection, error) {
+		return DialWithOptions(addr, opts)
 	})
 }
 
@@ -41,17 +43,50 @@ func validateAddr(addr string) (string, error) {
 // The Client returned is ready to make calls to the OrientDB but has not
 // yet established a database session or a session with the OrientDB server.
 // After this, the user needs to call either OpenDatabase or CreateServerSession.
+//
+// Address may contain a list of cluster nodes (see orient.ParseAddrs).
+// In this case nodes are tried in order, and connection is made to the first one available.
 func Dial(addr string) (*Client, error) {
+	return DialWithOptions(addr, orient.Options{})
+}
+
+// DialWithOptions is like Dial, but allows to set connection options, like TLS config.
+func DialWithOptions(addr string, opts orient.Options) (*Client, error) {
+	addrs, err := orient.ParseAddrs(addr)
+	if err != nil {
+		return nil, err
+	}
+	var last error
+	for _, addr := range addrs {
+		c, err := dial(addr, opts)
+		if err == nil {
+			return c, nil
+		}
+		last = err
+	}
+	return nil, last
+}
+
+func dial(addr string, opts orient.Options) (*Client, error) {
 	addr, err := validateAddr(addr)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.DialTimeout("tcp", addr, time.Minute)
+	timeout := opts.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	var conn net.Conn
+	if opts.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, opts.TLSConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, timeout)
+	}
 	if err != nil {
 		return nil, err
 	}
 	c := &Client{
-		addr: addr, conn: conn, done: make(chan struct{}),
+		addr: addr, opts: opts, conn: conn, done: make(chan struct{}),
 		br: bufio.NewReader(conn), bw: bufio.NewWriter(conn),
 	}
 	c.pr = rw.NewReader(c.br)
@@ -74,6 +109,7 @@ func Dial(addr string) (*Client, error) {
 // or OpenDatabase, to connect to a database on the server.
 type Client struct {
 	addr string
+	opts orient.Options
 
 	done chan struct{}
 
@@ -92,6 +128,9 @@ type Client struct {
 	currmu sync.RWMutex
 	currdb *Database // only one db session open at a time
 
+	livemu sync.Mutex
+	live   map[int32]func(orient.LiveEvent) // live query handlers by token
+
 	srvProtoVers int
 	curProtoVers int
 
@@ -99,7 +138,11 @@ type Client struct {
 }
 
 func (c *Client) handshakeVersion() error {
-	c.conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	timeout := c.opts.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultHandshakeTimeout
+	}
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
 	defer c.conn.SetReadDeadline(time.Time{})
 
 	c.srvProtoVers = int(c.pr.ReadShort())
@@ -114,7 +157,11 @@ func (c *Client) handshakeVersion() error {
 		log.Printf("OrientDB version is unsupported by driver: %d vs %d. Will fallback to protocol %d.",
 			MaxProtocolVersion, c.srvProtoVers, CurrentProtoVersion)
 	}
-	c.recordFormat = orient.GetDefaultRecordSerializer()
+	if c.opts.RecordFormat != "" {
+		c.recordFormat = orient.GetRecordFormat(c.opts.RecordFormat)
+	} else {
+		c.recordFormat = orient.GetDefaultRecordSerializer()
+	}
 	c.curProtoVers = CurrentProtoVersion
 	if c.curProtoVers > c.srvProtoVers {
 		c.curProtoVers = c.srvProtoVers
@@ -122,11 +169,14 @@ func (c *Client) handshakeVersion() error {
 	return nil
 }
 
-func (c *Client) writeCmd(op byte, sid int32, wr func(*rw.Writer) error) error {
+func (c *Client) writeCmd(op byte, s *session, wr func(*rw.Writer) error) error {
 	c.cmuw.Lock()
 	defer c.cmuw.Unlock()
 	c.pw.WriteByte(op)
-	c.pw.WriteInt(sid)
+	c.pw.WriteInt(s.id)
+	if tok := s.getToken(); tok != nil {
+		c.pw.WriteBytes(tok)
+	}
 	if wr != nil {
 		if err := wr(c.pw); err != nil {
 			return err
@@ -140,7 +190,7 @@ func (c *Client) newSess(id int32) *session {
 	c.sessmu.Lock()
 	s := c.sess[id]
 	if s == nil {
-		s = &session{id: id, cli: c, in: make(chan resp)}
+		s = &session{id: id, cli: c, in: make(chan resp), lock: make(chan struct{}, 1)}
 		c.sess[id] = s
 	}
 	c.sessmu.Unlock()
@@ -234,10 +284,15 @@ func readErrorResponse(r *rw.Reader, protoVers int) (serverException error) {
 		exc = append(exc, orient.UnknownException{Class: exClass, Message: exMsg})
 	}
 
-	// Next there is a serialized exception of bytes, but it is only
-	// useful to Java clients, so read and ignore if present.
+	// Next there is a serialized Java exception. It can be deserialized only by Java clients,
+	// but it's kept in exceptions to allow inspecting the server stack.
 	if protoVers >= ProtoVersion19 {
-		_ = r.ReadBytes()
+		stack := r.ReadBytes()
+		for i, e := range exc {
+			ue := e.(orient.UnknownException)
+			ue.Stack = stack
+			exc[i] = ue
+		}
 	}
 
 	for _, e := range exc {
@@ -250,6 +305,7 @@ func readErrorResponse(r *rw.Reader, protoVers int) (serverException error) {
 }
 
 func (c *Client) run() error {
+	defer c.closeLive()
 	defer close(c.done)
 	var (
 		status byte
@@ -263,28 +319,75 @@ func (c *Client) run() error {
 		}
 		switch status {
 		case responseStatusOk:
+			if err := c.readRenewedToken(sessId); err != nil {
+				return err
+			}
 			c.pushResp(sessId, c.br, nil)
 		case responseStatusError:
+			if err := c.readRenewedToken(sessId); err != nil {
+				return err
+			}
 			e := readErrorResponse(c.pr, c.curProtoVers)
 			c.pushResp(sessId, nil, e)
 		case responseStatusPush:
-			return ErrBrokenProtocol{fmt.Errorf("server push is not supported yet")}
+			tp := c.pr.ReadByte()
+			data := c.pr.ReadBytes()
+			if err := c.pr.Err(); err != nil {
+				return err
+			}
+			if err := c.handlePush(tp, data); err != nil {
+				return ErrBrokenProtocol{err}
+			}
 		default:
 			return ErrBrokenProtocol{fmt.Errorf("unknown resp status: %d", status)}
 		}
 	}
 }
 
+// readRenewedToken reads token field of a response header. It is sent by server only for token-based sessions,
+// and it is empty unless the token was renewed.
+func (c *Client) readRenewedToken(sessId int32) error {
+	c.sessmu.RLock()
+	s := c.sess[sessId]
+	c.sessmu.RUnlock()
+	if s == nil || s.getToken() == nil {
+		return nil
+	}
+	tok := c.pr.ReadBytes()
+	if err := c.pr.Err(); err != nil {
+		return err
+	}
+	if len(tok) != 0 {
+		s.setToken(tok)
+	}
+	return nil
+}
+
 type resp struct {
 	io.ReadCloser
 	err error
 }
 
 type session struct {
-	mu  sync.Mutex
-	id  int32
-	in  chan resp
-	cli *Client
+	lock chan struct{} // acts as a mutex that can be abandoned on context cancellation
+	id   int32
+	in   chan resp
+	cli  *Client
+
+	tokmu sync.Mutex
+	token []byte // nil if session is not token-based
+}
+
+func (s *session) getToken() []byte {
+	s.tokmu.Lock()
+	defer s.tokmu.Unlock()
+	return s.token
+}
+
+func (s *session) setToken(tok []byte) {
+	s.tokmu.Lock()
+	s.token = tok
+	s.tokmu.Unlock()
 }
 
 func (s *session) catch(err *error) {
@@ -300,34 +403,131 @@ func (s *session) catch(err *error) {
 }
 
 func (s *session) sendCmd(op byte, wr func(*rw.Writer) error, rd func(*rw.Reader) error) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if err := s.cli.writeCmd(op, s.id, wr); err != nil {
+	return s.sendCmdContext(context.Background(), op, wr, rd)
+}
+
+// sendCmdContext sends a command to the server and waits for the response.
+//
+// If ctx is done before the response arrives, an error is returned immediately. The response is still consumed by rd
+// in a background goroutine to keep the connection stream usable, and the session stays locked until it is drained.
+// Thus, callers must not use any values set by rd if an error is returned. If Options.ReadTimeout expires, the
+// connection is closed and ErrReadTimeout is returned.
+func (s *session) sendCmdContext(ctx context.Context, op byte, wr func(*rw.Writer) error, rd func(*rw.Reader) error) error {
+	body, unlock, err := s.waitResp(ctx, op, wr, rd)
+	if err != nil {
 		return err
 	}
-	if op == requestDbClose {
+	defer unlock()
+	if body == nil {
 		return nil
 	}
+	defer body.Close()
+	return readBody(body, rd)
+}
+
+// sendCmdStream is like sendCmdContext, but returns a reader of the response instead of reading it with a callback,
+// so the response can be decoded incrementally. The session is locked and the connection is blocked until returned
+// function is called. Response must be read completely before that, otherwise the connection stream will be broken.
+//
+// Function drain is used to consume the response if ctx is done before it arrives.
+func (s *session) sendCmdStream(ctx context.Context, op byte, wr func(*rw.Writer) error, drain func(*rw.Reader) error) (*rw.Reader, func(), error) {
+	body, unlock, err := s.waitResp(ctx, op, wr, drain)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rw.NewReader(body), func() {
+		body.Close()
+		unlock()
+	}, nil
+}
+
+// waitResp locks the session, sends a command and waits for the response. On success, response body (nil for
+// commands without response) and a function that unlocks the session are returned; caller must close the body and
+// unlock the session. On error the session is either unlocked, or will be unlocked after drain consumes the response.
+// On read timeout the connection is closed.
+func (s *session) waitResp(ctx context.Context, op byte, wr func(*rw.Writer) error, drain func(*rw.Reader) error) (io.ReadCloser, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	select {
+	case s.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	unlock := func() { <-s.lock }
+	if err := s.cli.writeCmd(op, s, wr); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	if op == requestDbClose {
+		return nil, unlock, nil
+	}
+	var timeout <-chan time.Time
+	if dt := s.cli.opts.ReadTimeout; dt > 0 {
+		t := time.NewTimer(dt)
+		defer t.Stop()
+		timeout = t.C
+	}
 	select {
 	case <-s.cli.done:
-		return fmt.Errorf("server gone")
+		unlock()
+		return nil, nil, ErrClosedConnection
 	case resp, ok := <-s.in:
-		if !ok {
-			return ErrClosedConnection
-		} else if resp.err != nil {
-			return resp.err
+		if err := respErr(resp, ok); err != nil {
+			unlock()
+			return nil, nil, err
 		}
-		defer resp.Close()
-		if rd != nil {
-			br := rw.NewReader(resp.ReadCloser.(io.Reader))
-			if err := rd(br); err != nil {
-				return err
-			} else if err = br.Err(); err != nil {
-				return err
-			}
+		return resp.ReadCloser, unlock, nil
+	case <-ctx.Done():
+		go s.drainResp(drain)
+		return nil, nil, ctx.Err()
+	case <-timeout:
+		// server may never respond, thus the connection is closed instead of waiting for the response
+		s.cli.conn.Close()
+		unlock()
+		return nil, nil, ErrReadTimeout
+	}
+}
+
+// drainResp waits for a response of abandoned command, reads it and unlocks the session.
+func (s *session) drainResp(rd func(*rw.Reader) error) {
+	var err error
+	defer func() { <-s.lock }()
+	defer s.catch(&err)
+	select {
+	case <-s.cli.done:
+	case resp, ok := <-s.in:
+		err = s.readResp(resp, ok, rd)
+	}
+}
+
+func (s *session) readResp(resp resp, ok bool, rd func(*rw.Reader) error) error {
+	if err := respErr(resp, ok); err != nil {
+		return err
+	}
+	defer resp.Close()
+	return readBody(resp.ReadCloser, rd)
+}
+
+// respErr returns an error for a closed connection or a server error response.
+func respErr(resp resp, ok bool) error {
+	if !ok {
+		return ErrClosedConnection
+	}
+	return resp.err
+}
+
+// readBody reads response body with rd, if it's set.
+func readBody(body io.Reader, rd func(*rw.Reader) error) error {
+	if rd != nil {
+		br := rw.NewReader(body)
+		if err := rd(br); err != nil {
+			return err
+		} else if err = br.Err(); err != nil {
+			return err
 		}
-		return nil
 	}
+	return nil
 }
 
 func (c *Client) getCurrDB() *Database {
@@ -1,6 +1,7 @@
 package obinary
 
 import (
+	"context"
 	"fmt"
 	"gopkg.in/istreamdata/orientgo.v2"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
@@ -10,15 +11,57 @@ func (db *Database) serializer() orient.RecordSerializer {
 	return db.sess.cli.recordFormat
 }
 
+// SetRecordCache sets a cache for records loaded by this session. Cache can be shared between sessions.
+func (db *Database) SetRecordCache(c *orient.RecordCache) {
+	db.cache = c
+}
+
 func (db *Database) updateCachedRecord(rec orient.ORecord) {
-	// TODO: implement records cache
+	db.cache.Put(rec)
+}
+
+// prefetchCache returns a cache for records prefetched by the server. If records cache is disabled, a temporary
+// cache is returned, so links of a single response can still be resolved.
+func (db *Database) prefetchCache() *orient.RecordCache {
+	if db.cache != nil {
+		return db.cache
+	}
+	return orient.NewRecordCache(0)
+}
+
+// resolveLinks replaces links in result documents with records prefetched by the server.
+func resolveLinks(prefetched *orient.RecordCache, result interface{}) {
+	switch res := result.(type) {
+	case *orient.Document:
+		prefetched.ResolveLinks(res)
+	case []orient.OIdentifiable:
+		for _, rec := range res {
+			if doc, ok := rec.(*orient.Document); ok {
+				prefetched.ResolveLinks(doc)
+			}
+		}
+	}
 }
 
 func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error) {
-	resType := rune(r.ReadByte())
+	resType := r.ReadByte()
 	if err = r.Err(); err != nil {
 		return nil, err
 	}
+	result, err = db.readSynchValue(r, resType)
+	if err != nil {
+		return nil, err
+	}
+	prefetched, err := db.readPrefetched(r)
+	if err != nil {
+		return result, err
+	}
+	resolveLinks(prefetched, result)
+	return result, r.Err()
+}
+
+// readSynchValue reads a result of synchronous command of a given type.
+func (db *Database) readSynchValue(r *rw.Reader, resType byte) (result interface{}, err error) {
 	switch resType {
 	case 'n': // null result
 		result = nil
@@ -64,6 +107,18 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 			}
 		}
 		result = recs
+	case 'w': // simple value wrapped into a document, since protocol 35
+		rec, err := db.readIdentifiable(r)
+		if err != nil {
+			return nil, err
+		}
+		if doc, ok := rec.(*orient.Document); ok {
+			if fld := doc.GetField("result"); fld != nil {
+				result = fld.Value
+			}
+		} else {
+			result = rec
+		}
 	case 'a': // serialized type
 		s := r.ReadString()
 		if err = r.Err(); err != nil {
@@ -74,31 +129,45 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 	default:
 		panic(fmt.Errorf("readSynchResult: not supported result type %v", resType))
 	}
-	if db.sess.cli.curProtoVers >= ProtoVersion17 {
-		for {
-			status := r.ReadByte()
-			if status <= 0 {
-				break
-			}
-			rec, err := db.readIdentifiable(r)
-			if err != nil {
-				return result, err
-			}
-			if rec != nil && status == 2 {
-				if rec, ok := rec.(orient.ORecord); ok {
-					db.updateCachedRecord(rec)
+	return result, r.Err()
+}
+
+// readPrefetched reads records prefetched by the server according to a fetch plan, which are sent after the command
+// result since protocol 17. Records are stored to cache. It reports if any records were prefetched.
+func (db *Database) readPrefetched(r *rw.Reader) (prefetched *orient.RecordCache, err error) {
+	if db.sess.cli.curProtoVers < ProtoVersion17 {
+		return nil, nil
+	}
+	for {
+		status := r.ReadByte()
+		if status <= 0 {
+			break
+		}
+		rec, err := db.readIdentifiable(r)
+		if err != nil {
+			return prefetched, err
+		}
+		if rec != nil && status == 2 {
+			if rec, ok := rec.(orient.ORecord); ok {
+				if prefetched == nil {
+					prefetched = db.prefetchCache()
 				}
+				prefetched.Put(rec)
 			}
 		}
 	}
-	return result, r.Err()
+	return prefetched, r.Err()
 }
 
 func (db *Database) Command(cmd orient.CustomSerializable) (result interface{}, err error) {
-	var data []byte
-	data, err = orient.SerializeAnyStreamable(cmd)
+	return db.CommandContext(context.Background(), cmd)
+}
+
+// CommandContext is like Command, but allows to cancel the request with a context.
+func (db *Database) CommandContext(ctx context.Context, cmd orient.CustomSerializable) (interface{}, error) {
+	data, err := orient.SerializeAnyStreamable(cmd)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	live, async := false, false // synchronous only supported for now
@@ -107,7 +176,8 @@ func (db *Database) Command(cmd orient.CustomSerializable) (result interface{},
 	// [(synch-result-type:byte)[(synch-result-content:?)]]+
 	// so the final value will by byte(0) to indicate the end of the array
 	// and we must use a loop here
-	err = db.sess.sendCmd(requestCommand, func(w *rw.Writer) error {
+	var result interface{}
+	err = db.sess.sendCmdContext(ctx, requestCommand, func(w *rw.Writer) error {
 		if live {
 			w.WriteByte(byte('l'))
 		} else if async {
@@ -121,6 +191,7 @@ func (db *Database) Command(cmd orient.CustomSerializable) (result interface{},
 		if async {
 			// TODO: async
 		} else {
+			var err error
 			result, err = db.readSynchResult(r)
 			if err != nil {
 				return err
@@ -131,5 +202,8 @@ func (db *Database) Command(cmd orient.CustomSerializable) (result interface{},
 		}
 		return r.Err()
 	})
-	return result, err
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
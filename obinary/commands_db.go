@@ -1,14 +1,19 @@
 package obinary
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"strings"
 
+	"github.com/nu7hatch/gouuid"
 	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/binserde"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 )
 
-func (c *Client) sendClientInfo(w *rw.Writer) {
+func (c *Client) sendClientInfo(w *rw.Writer, useToken bool) {
 	if c.curProtoVers >= ProtoVersion7 {
 		w.WriteStrings(driverName, driverVersion) // driver info
 		w.WriteShort(int16(c.curProtoVers))       // protocol version
@@ -20,31 +25,35 @@ func (c *Client) sendClientInfo(w *rw.Writer) {
 		panic("CSV serializer is not supported")
 	}
 	if c.curProtoVers > ProtoVersion26 {
-		w.WriteBool(false) // use token (true) or session (false)
+		w.WriteBool(useToken) // use token (true) or session (false)
+	}
+	if c.curProtoVers > ProtoVersion33 {
+		w.WriteBool(true)  // support push messages
+		w.WriteBool(false) // collect stats
 	}
 }
 
-func (c *Client) openDBSess(dbname string, dbtype orient.DatabaseType, user, pass string) (*session, *ODatabase, error) {
+func (c *Client) openDBSess(ctx context.Context, dbname string, dbtype orient.DatabaseType, user, pass string) (*session, *ODatabase, error) {
 	var (
-		sessId int32
-		//token []byte
+		sessId     int32
+		token      []byte
 		clusters   []OCluster
 		clusterCfg []byte
 		//serverVers string
 	)
-	err := c.root.sendCmd(requestDbOpen, func(w *rw.Writer) error {
-		c.sendClientInfo(w)
+	err := c.root.sendCmdContext(ctx, requestDbOpen, func(w *rw.Writer) error {
+		c.sendClientInfo(w, c.opts.UseToken)
 
 		w.WriteString(dbname)
-		if c.curProtoVers >= ProtoVersion8 {
-			w.WriteString(string(dbtype))
+		if c.curProtoVers >= ProtoVersion8 && c.curProtoVers <= ProtoVersion32 {
+			w.WriteString(string(dbtype)) // not used by server since protocol 33
 		}
 		w.WriteString(user)
 		w.WriteString(pass)
 		return w.Err()
 	}, func(r *rw.Reader) error {
-		sessId = r.ReadInt() // new session id
-		_ = r.ReadBytes()    // token - may ignore this in session mode (is nil)
+		sessId = r.ReadInt()  // new session id
+		token = r.ReadBytes() // token - nil in session mode
 
 		n := int(r.ReadShort())
 		clusters = make([]OCluster, n)
@@ -63,6 +72,9 @@ func (c *Client) openDBSess(dbname string, dbtype orient.DatabaseType, user, pas
 		return nil, nil, fmt.Errorf("wrong session id returned: %d", sessId)
 	}
 	sess := c.newSess(sessId)
+	if c.opts.UseToken && len(token) != 0 {
+		sess.setToken(token)
+	}
 	db := NewDatabase(dbname, dbtype)
 	db.Clusters = clusters
 	db.ClustCfg = clusterCfg
@@ -70,8 +82,9 @@ func (c *Client) openDBSess(dbname string, dbtype orient.DatabaseType, user, pas
 }
 
 type Database struct {
-	sess *session
-	db   *ODatabase
+	sess  *session
+	db    *ODatabase
+	cache *orient.RecordCache // nil if records are not cached
 }
 
 // OpenDatabase sends the REQUEST_DB_OPEN command to the OrientDb server to
@@ -79,32 +92,101 @@ type Database struct {
 // username and password.  Database type should be one of the obinary constants:
 // DocumentDbType or GraphDbType.
 func (c *Client) OpenDatabase(dbname string, dbtype orient.DatabaseType, user, pass string) (db *Database, err error) {
+	return c.OpenDatabaseContext(context.Background(), dbname, dbtype, user, pass)
+}
+
+// OpenDatabaseContext is like OpenDatabase, but allows to cancel the request with a context.
+func (c *Client) OpenDatabaseContext(ctx context.Context, dbname string, dbtype orient.DatabaseType, user, pass string) (db *Database, err error) {
 	// TODO: close previous DB? will connection drop in this case?
-	var (
-		sess *session
-		odb  *ODatabase
-	)
-	sess, odb, err = c.openDBSess(dbname, dbtype, user, pass)
+	sess, odb, err := c.openDBSess(ctx, dbname, dbtype, user, pass)
 	if err != nil {
 		return nil, err
 	}
-	db = &Database{sess: sess, db: odb}
+	return c.initDatabase(ctx, &Database{sess: sess, db: odb})
+}
+
+// initDatabase makes database current for this connection and loads schema.
+func (c *Client) initDatabase(ctx context.Context, db *Database) (*Database, error) {
 	c.currmu.Lock()
 	c.currdb = db
 	c.currmu.Unlock()
-	err = db.refreshGlobalProperties()
+	err := db.refreshGlobalProperties(ctx)
 	c.recordFormat.SetGlobalPropertyFunc(func(id int) (orient.OGlobalProperty, bool) {
 		// TODO: implement global property lookup
-		db.refreshGlobalPropertiesIfRequired(id)
+		db.refreshGlobalPropertiesIfRequired(context.Background(), id)
 		return db.db.GetGlobalProperty(id)
 	})
 	return db, err
 }
 
+// OpenDatabaseWithToken restores token-based database session on this connection.
+// Token is taken from another session (see Database.Token), so no credentials are sent to the server.
+func (c *Client) OpenDatabaseWithToken(dbname string, dbtype orient.DatabaseType, tok orient.SessionToken) (*Database, error) {
+	return c.OpenDatabaseWithTokenContext(context.Background(), dbname, dbtype, tok)
+}
+
+// OpenDatabaseWithTokenContext is like OpenDatabaseWithToken, but allows to cancel the request with a context.
+func (c *Client) OpenDatabaseWithTokenContext(ctx context.Context, dbname string, dbtype orient.DatabaseType, tok orient.SessionToken) (*Database, error) {
+	if len(tok.Token) == 0 {
+		return nil, fmt.Errorf("empty session token")
+	} else if c.curProtoVers <= ProtoVersion26 {
+		return nil, fmt.Errorf("token sessions are not supported by protocol %d", c.curProtoVers)
+	}
+	sess := c.newSess(tok.SessionID)
+	sess.setToken(tok.Token)
+	db := &Database{sess: sess, db: NewDatabase(dbname, dbtype)}
+	// cluster list is returned only by DB_OPEN, so reload it; it also checks that token is still valid
+	if err := db.reloadClusters(ctx); err != nil {
+		c.closeSess(sess.id, db)
+		return nil, err
+	}
+	return c.initDatabase(ctx, db)
+}
+
+// reloadClusters fetches a list of clusters with REQUEST_DB_RELOAD.
+func (db *Database) reloadClusters(ctx context.Context) error {
+	var clusters []OCluster
+	err := db.sess.sendCmdContext(ctx, requestDbRELOAD, nil, func(r *rw.Reader) error {
+		n := int(r.ReadShort())
+		clusters = make([]OCluster, n)
+		for i := range clusters {
+			name := r.ReadString()
+			id := r.ReadShort()
+			clusters[i] = OCluster{Name: name, Id: id}
+		}
+		return r.Err()
+	})
+	if err != nil {
+		return err
+	}
+	db.db.Clusters = clusters
+	return nil
+}
+
+// Token returns session token, if database session is token-based.
+func (db *Database) Token() orient.SessionToken {
+	if db == nil || db.sess == nil {
+		return orient.SessionToken{}
+	}
+	tok := db.sess.getToken()
+	if tok == nil {
+		return orient.SessionToken{}
+	}
+	return orient.SessionToken{SessionID: db.sess.id, Token: tok}
+}
+
 func (c *Client) Open(dbname string, dbtype orient.DatabaseType, user, pass string) (orient.DBSession, error) {
 	return c.OpenDatabase(dbname, dbtype, user, pass)
 }
 
+func (c *Client) OpenContext(ctx context.Context, dbname string, dbtype orient.DatabaseType, user, pass string) (orient.DBSession, error) {
+	return c.OpenDatabaseContext(ctx, dbname, dbtype, user, pass)
+}
+
+func (c *Client) OpenTokenContext(ctx context.Context, dbname string, dbtype orient.DatabaseType, tok orient.SessionToken) (orient.DBSession, error) {
+	return c.OpenDatabaseWithTokenContext(ctx, dbname, dbtype, tok)
+}
+
 // refreshGlobalPropertiesIfRequired iterates through all the fields
 // of the binserde header. If any of the fieldIds are NOT in the GlobalProperties
 // map of the current ODatabase object, then the GlobalProperties are
@@ -113,12 +195,12 @@ func (c *Client) Open(dbname string, dbtype orient.DatabaseType, user, pass stri
 //
 // If the GlobalProperties data is stale, then it must be refreshed, so
 // refreshGlobalProperties is called.
-func (db *Database) refreshGlobalPropertiesIfRequired(id int) error {
+func (db *Database) refreshGlobalPropertiesIfRequired(ctx context.Context, id int) error {
 	if db == nil || db.db == nil {
 		return nil
 	}
 	if _, ok := db.db.GetGlobalProperty(id); !ok {
-		return db.refreshGlobalProperties()
+		return db.refreshGlobalProperties(ctx)
 	}
 	return nil
 }
@@ -126,27 +208,82 @@ func (db *Database) refreshGlobalPropertiesIfRequired(id int) error {
 // refreshGlobalProperties is called when it is discovered,
 // while in the middle of reading the response from the OrientDB
 // server, that the GlobalProperties are stale.
-func (db *Database) refreshGlobalProperties() error {
+func (db *Database) refreshGlobalProperties(ctx context.Context) error {
 	// ---[ load #0:0 - config record ]---
-	oschemaRID, err := db.loadConfigRecord()
+	oschemaRID, err := db.loadConfigRecord(ctx)
 	if err != nil {
 		return err
 	}
 	// ---[ load #0:1 - oschema record ]---
-	err = db.loadSchema(oschemaRID)
+	err = db.loadSchema(ctx, oschemaRID)
 	if err != nil {
 		return err
 	}
+	db.applyTimeZone()
 	return nil
 }
 
+// applyTimeZone sets database time zone for record serializers, so DATE and DATETIME values
+// are converted the same way as in Java clients.
+func (db *Database) applyTimeZone() {
+	db.db.storageMu.RLock()
+	loc := db.db.StorageCfg.location()
+	db.db.storageMu.RUnlock()
+	if ts, ok := db.serializer().(orient.TimeZoneSetter); ok {
+		ts.SetTimeZone(loc)
+	}
+}
+
+// StorageInfo returns storage configuration of the database. Configuration is reloaded from the server,
+// so the list of clusters is up to date.
+func (db *Database) StorageInfo() (*orient.StorageInfo, error) {
+	return db.StorageInfoContext(context.Background())
+}
+
+// StorageInfoContext is like StorageInfo, but allows to cancel the request with a context.
+func (db *Database) StorageInfoContext(ctx context.Context) (*orient.StorageInfo, error) {
+	if _, err := db.loadConfigRecord(ctx); err != nil {
+		return nil, err
+	}
+	db.db.storageMu.RLock()
+	sc := db.db.StorageCfg
+	db.db.storageMu.RUnlock()
+	info := &orient.StorageInfo{
+		Name:           sc.name,
+		Version:        int(sc.version),
+		DateFormat:     sc.dateFmt,
+		DateTimeFormat: sc.dateTimeFmt,
+		TimeZone:       sc.timezone,
+		LocaleLanguage: sc.localeLang,
+		LocaleCountry:  sc.localeCountry,
+		Charset:        sc.charset,
+	}
+	if sc.clusters != nil {
+		info.Clusters = append([]orient.ClusterInfo(nil), sc.clusters...)
+	} else {
+		for _, c := range db.db.Clusters {
+			info.Clusters = append(info.Clusters, orient.ClusterInfo{ID: c.Id, Name: c.Name})
+		}
+	}
+	classes := make(map[int16]string)
+	for _, cl := range db.db.Classes {
+		for _, id := range cl.ClusterIds {
+			classes[int16(id)] = cl.Name
+		}
+	}
+	for i := range info.Clusters {
+		info.Clusters[i].Class = classes[info.Clusters[i].ID]
+	}
+	return info, nil
+}
+
 // loadConfigRecord loads record #0:0 for the current database, caching
 // some of the information returned into OStorageConfiguration
-func (db *Database) loadConfigRecord() (orient.RID, error) {
+func (db *Database) loadConfigRecord(ctx context.Context) (orient.RID, error) {
 	// The config record comes back as type 'b' (raw bytes), which should
 	// just be converted to a string then tokenized by the pipe char
 	rid := orient.RID{ClusterID: 0, ClusterPos: 0}
-	rec, err := db.GetRecordByRID(rid, "*:-1 index:0", true) // based on Java client code
+	rec, err := db.GetRecordByRIDContext(ctx, rid, "*:-1 index:0", true) // based on Java client code
 	if err != nil {
 		return orient.NewEmptyRID(), err
 	}
@@ -157,20 +294,27 @@ func (db *Database) loadConfigRecord() (orient.RID, error) {
 		return orient.NewEmptyRID(), fmt.Errorf("config record is empty")
 	}
 	sc := &OStorageConfiguration{}
-	if err = sc.parse(string(raw.Data)); err != nil {
+	if err = sc.parse(string(raw.Data), db.sess.cli.curProtoVers); err != nil {
 		return orient.NewEmptyRID(), fmt.Errorf("config parse error: %s", err)
 	}
 	db.db.storageMu.Lock()
 	db.db.StorageCfg = *sc
 	db.db.storageMu.Unlock()
+	if sc.clusters != nil {
+		clusters := make([]OCluster, 0, len(sc.clusters))
+		for _, c := range sc.clusters {
+			clusters = append(clusters, OCluster{Name: c.Name, Id: c.ID})
+		}
+		db.db.Clusters = clusters
+	}
 	return sc.schemaRID, nil
 }
 
 // loadSchema loads record #0:1 for the current database, caching the
 // SchemaVersion, GlobalProperties and Classes info in the current ODatabase
 // object (dbc.currDb).
-func (db *Database) loadSchema(rid orient.RID) error {
-	rec, err := db.GetRecordByRID(rid, "*:-1 index:0", true) // TODO: GetRecordByRIDIfChanged
+func (db *Database) loadSchema(ctx context.Context, rid orient.RID) error {
+	rec, err := db.GetRecordByRIDContext(ctx, rid, "*:-1 index:0", true) // TODO: GetRecordByRIDIfChanged
 	if err != nil {
 		return err
 	}
@@ -196,13 +340,16 @@ func (db *Database) loadSchema(rid orient.RID) error {
 	}
 
 	// ---[ classes ]---
+	// new map is created, so dropped classes are removed from the schema
 	var oclass *orient.OClass
+	classes := make(map[string]*orient.OClass)
 	classesFld := doc.GetField("classes")
 	for _, cfield := range classesFld.Value.([]interface{}) {
 		cdoc := cfield.(*orient.Document)
 		oclass = orient.NewOClassFromDocument(cdoc)
-		odb.Classes[oclass.Name] = oclass
+		classes[oclass.Name] = oclass
 	}
+	odb.Classes = classes
 	return nil
 }
 
@@ -221,18 +368,55 @@ func (db *Database) Close() error {
 	return err
 }
 
+// Alive checks if connection to the server is still open, without sending any requests.
+func (db *Database) Alive() bool {
+	if db == nil || db.sess == nil {
+		return false
+	}
+	select {
+	case <-db.sess.cli.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Ping checks that database session is still usable by sending a light-weight request to the server.
+func (db *Database) Ping() error {
+	return db.PingContext(context.Background())
+}
+
+// PingContext is like Ping, but allows to cancel the request with a context.
+func (db *Database) PingContext(ctx context.Context) error {
+	if !db.Alive() {
+		return ErrClosedConnection
+	}
+	_, err := db.getLongFromDB(ctx, requestDbSIZE)
+	return err
+}
+
 // FetchDatabaseSize retrieves the size of the current database in bytes.
 // It is a database-level operation, so OpenDatabase must have already
 // been called first in order to start a session with the database.
 func (db *Database) Size() (int64, error) {
-	return db.getLongFromDB(requestDbSIZE)
+	return db.SizeContext(context.Background())
+}
+
+// SizeContext is like Size, but allows to cancel the request with a context.
+func (db *Database) SizeContext(ctx context.Context) (int64, error) {
+	return db.getLongFromDB(ctx, requestDbSIZE)
 }
 
 // FetchNumRecordsInDatabase retrieves the number of records of the current
 // database. It is a database-level operation, so OpenDatabase must have
 // already been called first in order to start a session with the database.
 func (db *Database) CountRecords() (int64, error) {
-	return db.getLongFromDB(requestDbCOUNTRECORDS)
+	return db.CountRecordsContext(context.Background())
+}
+
+// CountRecordsContext is like CountRecords, but allows to cancel the request with a context.
+func (db *Database) CountRecordsContext(ctx context.Context) (int64, error) {
+	return db.getLongFromDB(ctx, requestDbCOUNTRECORDS)
 }
 
 // DeleteRecordByRID deletes a record specified by its RID and its version.
@@ -241,8 +425,13 @@ func (db *Database) CountRecords() (int64, error) {
 // If error is returned, delete request was either never issued, or there was
 // a problem on the server end or the record did not exist in the database.
 func (db *Database) DeleteRecordByRID(rid orient.RID, recVersion int) error {
+	return db.DeleteRecordByRIDContext(context.Background(), rid, recVersion)
+}
+
+// DeleteRecordByRIDContext is like DeleteRecordByRID, but allows to cancel the request with a context.
+func (db *Database) DeleteRecordByRIDContext(ctx context.Context, rid orient.RID, recVersion int) error {
 	var status byte
-	err := db.sess.sendCmd(requestRecordDELETE, func(w *rw.Writer) error {
+	err := db.sess.sendCmdContext(ctx, requestRecordDELETE, func(w *rw.Writer) error {
 		if err := rid.ToStream(w); err != nil {
 			return err
 		}
@@ -253,6 +442,7 @@ func (db *Database) DeleteRecordByRID(rid orient.RID, recVersion int) error {
 		status = r.ReadByte()
 		return r.Err()
 	})
+	db.cache.Remove(rid)
 	if err != nil {
 		return err
 	}
@@ -264,11 +454,104 @@ func (db *Database) DeleteRecordByRID(rid orient.RID, recVersion int) error {
 	return nil
 }
 
+// RecordMetadata returns current version of a record without loading its content (REQUEST_RECORD_METADATA).
+func (db *Database) RecordMetadata(rid orient.RID) (int, error) {
+	return db.RecordMetadataContext(context.Background(), rid)
+}
+
+// RecordMetadataContext is like RecordMetadata, but allows to cancel the request with a context.
+func (db *Database) RecordMetadataContext(ctx context.Context, rid orient.RID) (int, error) {
+	var version int
+	err := db.sess.sendCmdContext(ctx, requestRecordMETADATA, func(w *rw.Writer) error {
+		if err := rid.ToStream(w); err != nil {
+			return err
+		}
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		var rrid orient.RID
+		if err := rrid.FromStream(r); err != nil {
+			return err
+		}
+		version = int(r.ReadInt())
+		return r.Err()
+	})
+	return version, err
+}
+
+// HideRecord marks a record as deleted without reading its content (REQUEST_RECORD_HIDE).
+func (db *Database) HideRecord(rid orient.RID) error {
+	return db.HideRecordContext(context.Background(), rid)
+}
+
+// HideRecordContext is like HideRecord, but allows to cancel the request with a context.
+func (db *Database) HideRecordContext(ctx context.Context, rid orient.RID) error {
+	var status byte
+	err := db.sess.sendCmdContext(ctx, requestRecordHIDE, func(w *rw.Writer) error {
+		if err := rid.ToStream(w); err != nil {
+			return err
+		}
+		w.WriteByte(0) // sync mode
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		status = r.ReadByte()
+		return r.Err()
+	})
+	db.cache.Remove(rid)
+	if err != nil {
+		return err
+	} else if status == byte(0) {
+		return fmt.Errorf("Record %s was not hidden. Either failed or did not exist.", rid)
+	}
+	return nil
+}
+
+// CleanOutRecord removes a record and releases its cluster position (REQUEST_RECORD_CLEAN_OUT).
+func (db *Database) CleanOutRecord(rid orient.RID, recVersion int) error {
+	return db.CleanOutRecordContext(context.Background(), rid, recVersion)
+}
+
+// CleanOutRecordContext is like CleanOutRecord, but allows to cancel the request with a context.
+func (db *Database) CleanOutRecordContext(ctx context.Context, rid orient.RID, recVersion int) error {
+	var status byte
+	err := db.sess.sendCmdContext(ctx, requestRecordCLEAN_OUT, func(w *rw.Writer) error {
+		if err := rid.ToStream(w); err != nil {
+			return err
+		}
+		w.WriteInt(int32(recVersion))
+		w.WriteByte(0) // sync mode
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		status = r.ReadByte()
+		return r.Err()
+	})
+	db.cache.Remove(rid)
+	if err != nil {
+		return err
+	} else if status == byte(0) {
+		return fmt.Errorf("Record %s was not cleaned out. Either failed or did not exist.", rid)
+	}
+	return nil
+}
+
 // GetRecordByRID takes an RID and reads that record from the database.
 //
 // ignoreCache = true
-func (db *Database) GetRecordByRID(rid orient.RID, fetchPlan orient.FetchPlan, ignoreCache bool) (rec orient.ORecord, err error) {
-	err = db.sess.sendCmd(requestRecordLOAD, func(w *rw.Writer) error {
+func (db *Database) GetRecordByRID(rid orient.RID, fetchPlan orient.FetchPlan, ignoreCache bool) (orient.ORecord, error) {
+	return db.GetRecordByRIDContext(context.Background(), rid, fetchPlan, ignoreCache)
+}
+
+// GetRecordByRIDContext is like GetRecordByRID, but allows to cancel the request with a context.
+func (db *Database) GetRecordByRIDContext(ctx context.Context, rid orient.RID, fetchPlan orient.FetchPlan, ignoreCache bool) (orient.ORecord, error) {
+	if !ignoreCache {
+		if rec := db.cache.Get(rid); rec != nil {
+			return rec, nil
+		}
+	}
+	var (
+		rec        orient.ORecord
+		prefetched *orient.RecordCache
+	)
+	err := db.sess.sendCmdContext(ctx, requestRecordLOAD, func(w *rw.Writer) error {
 		if err := rid.ToStream(w); err != nil {
 			return err
 		}
@@ -319,35 +602,65 @@ func (db *Database) GetRecordByRID(rid orient.RID, fetchPlan orient.FetchPlan, i
 				return err
 			}
 			if rec, ok := rec.(orient.ORecord); ok {
-				db.updateCachedRecord(rec)
+				if prefetched == nil {
+					prefetched = db.prefetchCache()
+				}
+				prefetched.Put(rec)
 			}
 		}
 		return r.Err()
 	})
-	return rec, err
+	if err != nil {
+		return nil, err
+	} else if rec == nil {
+		db.cache.Remove(rid)
+		return nil, nil
+	}
+	db.updateCachedRecord(rec)
+	if doc, ok := rec.(*orient.Document); ok {
+		prefetched.ResolveLinks(doc)
+	}
+	return rec, nil
 }
 
 // ReloadSchema should be called after a schema is altered, such as properties
 // added, deleted or renamed.
 func (db *Database) ReloadSchema() error {
-	return db.loadSchema(orient.RID{ClusterID: 0, ClusterPos: 1})
+	return db.ReloadSchemaContext(context.Background())
+}
+
+// ReloadSchemaContext is like ReloadSchema, but allows to cancel the request with a context.
+func (db *Database) ReloadSchemaContext(ctx context.Context) error {
+	// DDL commands may create or drop clusters
+	if err := db.reloadClusters(ctx); err != nil {
+		return err
+	}
+	return db.refreshGlobalProperties(ctx)
 }
 
 // FetchClusterDataRange returns the range of record ids for a cluster
 func (db *Database) GetClusterDataRange(clusterName string) (begin, end int64, err error) {
-	var clusterID int16
-	clusterID, err = db.findClusterWithName(clusterName)
+	return db.GetClusterDataRangeContext(context.Background(), clusterName)
+}
+
+// GetClusterDataRangeContext is like GetClusterDataRange, but allows to cancel the request with a context.
+func (db *Database) GetClusterDataRangeContext(ctx context.Context, clusterName string) (begin, end int64, err error) {
+	clusterID, err := db.findClusterWithName(clusterName)
 	if err != nil {
-		return
+		return 0, 0, err
 	}
-	err = db.sess.sendCmd(requestDataClusterDATARANGE, func(w *rw.Writer) error {
+	var b, e int64
+	err = db.sess.sendCmdContext(ctx, requestDataClusterDATARANGE, func(w *rw.Writer) error {
 		return w.WriteShort(clusterID)
 	}, func(r *rw.Reader) error {
-		begin = r.ReadLong()
-		end = r.ReadLong()
+		b = r.ReadLong()
+		e = r.ReadLong()
 		return r.Err()
 	})
-	return begin, end, err
+	if err != nil {
+		return 0, 0, err
+	}
+	return b, e, nil
 }
 
 // AddClusterWithID adds a cluster to the current database with a given cluster position.
@@ -355,20 +668,26 @@ func (db *Database) GetClusterDataRange(clusterName string) (begin, end int64, e
 // been called first in order to start a session with the database.
 // The clusterID is returned if the command is successful.
 func (db *Database) AddClusterWithID(name string, id int16) (clusterID int16, err error) {
+	return db.AddClusterWithIDContext(context.Background(), name, id)
+}
+
+// AddClusterWithIDContext is like AddClusterWithID, but allows to cancel the request with a context.
+func (db *Database) AddClusterWithIDContext(ctx context.Context, name string, id int16) (clusterID int16, err error) {
 	name = strings.ToLower(name)
-	clusterID = id
-	err = db.sess.sendCmd(requestDataClusterADD, func(w *rw.Writer) error {
+	var cid int16
+	err = db.sess.sendCmdContext(ctx, requestDataClusterADD, func(w *rw.Writer) error {
 		w.WriteString(name)
-		w.WriteShort(clusterID)
+		w.WriteShort(id)
 		return w.Err()
 	}, func(r *rw.Reader) error {
-		clusterID = r.ReadShort()
+		cid = r.ReadShort()
 		return r.Err()
 	})
-	if err == nil {
-		db.db.Clusters = append(db.db.Clusters, OCluster{name, clusterID})
+	if err != nil {
+		return id, err
 	}
-	return clusterID, err
+	db.db.Clusters = append(db.db.Clusters, OCluster{name, cid})
+	return cid, nil
 }
 
 // DropCluster drops a cluster to the current database. It is a
@@ -376,12 +695,17 @@ func (db *Database) AddClusterWithID(name string, id int16) (clusterID int16, er
 // been called first in order to start a session with the database.
 // If nil is returned, then the action succeeded.
 func (db *Database) DropCluster(clusterName string) error {
+	return db.DropClusterContext(context.Background(), clusterName)
+}
+
+// DropClusterContext is like DropCluster, but allows to cancel the request with a context.
+func (db *Database) DropClusterContext(ctx context.Context, clusterName string) error {
 	clusterID, err := db.findClusterWithName(clusterName)
 	if err != nil {
 		return err
 	}
 	var status byte
-	err = db.sess.sendCmd(requestDataClusterDROP, func(w *rw.Writer) error {
+	err = db.sess.sendCmdContext(ctx, requestDataClusterDROP, func(w *rw.Writer) error {
 		return w.WriteShort(clusterID)
 	}, func(r *rw.Reader) error {
 		status = r.ReadByte()
@@ -393,139 +717,160 @@ func (db *Database) DropCluster(clusterName string) error {
 	return err
 }
 
-/*
-// FetchEntriesOfRemoteLinkBag fills in the links of an OLinkBag that is remote
-// (tree-based) rather than embedded.  This function will fill in the links
-// of the passed in OLinkBag, rather than returning the new links. The Links
-// will have RIDs only, not full Records (Documents).  If you then want the
-// Records filled in, call the ResolveLinks function.
-func (db *Database) GetEntriesOfRemoteLinkBag(linkBag *orient.OLinkBag, inclusive bool) (err error) {
-	defer catch(&err)
+// RidBagFirstKey sends REQUEST_SBTREE_BONSAI_FIRST_KEY to get the first link stored in tree-based RidBag.
+// Invalid RID is returned if the tree is empty.
+func (db *Database) RidBagFirstKey(ptr orient.BonsaiPointer) (orient.RID, error) {
+	return db.RidBagFirstKeyContext(context.Background(), ptr)
+}
+
+// RidBagFirstKeyContext is like RidBagFirstKey, but allows to cancel the request with a context.
+func (db *Database) RidBagFirstKeyContext(ctx context.Context, ptr orient.BonsaiPointer) (orient.RID, error) {
+	rid := orient.NewEmptyRID()
+	err := db.sess.sendCmdContext(ctx, requestSBTREE_BONSAI_FIRST_KEY, func(w *rw.Writer) error {
+		return ptr.ToStream(w)
+	}, func(r *rw.Reader) error {
+		data := r.ReadBytes()
+		if err := r.Err(); err != nil || len(data) == 0 {
+			return err
+		}
+		if data[0] != binserde.LinkSerializer {
+			return fmt.Errorf("RidBagFirstKey: unknown key type: %d", data[0])
+		}
+		return rid.FromStream(bytes.NewReader(data[1:]))
+	})
+	return rid, err
+}
+
+// RidBagEntriesMajor sends REQUEST_SBTREE_BONSAI_GET_ENTRIES_MAJOR to get up to pageSize links from tree-based RidBag
+// that are greater than (or equal to, if inclusive is set) a given key.
+func (db *Database) RidBagEntriesMajor(ptr orient.BonsaiPointer, key orient.RID, inclusive bool, pageSize int) ([]orient.RidBagEntry, error) {
+	return db.RidBagEntriesMajorContext(context.Background(), ptr, key, inclusive, pageSize)
+}
+
+// RidBagEntriesMajorContext is like RidBagEntriesMajor, but allows to cancel the request with a context.
+func (db *Database) RidBagEntriesMajorContext(ctx context.Context, ptr orient.BonsaiPointer, key orient.RID, inclusive bool, pageSize int) ([]orient.RidBagEntry, error) {
 	var (
-		firstLink orient.OIdentifiable
-		linkSerde = binserde.OLinkSerializer{}
+		entries []orient.RidBagEntry
+		err     error
 	)
-	firstLink, err = db.GetFirstKeyOfRemoteLinkBag(linkBag)
-	if err != nil {
-		return err
-	}
-	linkBytes, err := linkSerde.Serialize(firstLink)
-	if err != nil {
+	err = db.sess.sendCmdContext(ctx, requestSBTREE_BONSAI_GET_ENTRIES_MAJOR, func(w *rw.Writer) error {
+		if err := ptr.ToStream(w); err != nil {
+			return err
+		}
+		data, err := binserde.OLinkSerializer{}.Serialize(key)
+		if err != nil {
+			return err
+		}
+		w.WriteBytes(data)
+		w.WriteBool(inclusive)
+		w.WriteInt(int32(pageSize))
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		data := r.ReadBytes()
+		if err := r.Err(); err != nil {
+			return err
+		}
+		entries, err = readRidBagEntries(rw.NewReader(bytes.NewReader(data)))
 		return err
-	}
-
-	var linkEntryBytes []byte
-	err = db.sess.sendCmd(requestSBTREE_BONSAI_GET_ENTRIES_MAJOR, func(w io.Writer) {
-		writeLinkBagCollectionPointer(w, linkBag)
-		rw.WriteBytes(w, linkBytes)
-		rw.WriteBool(w, inclusive)
-		rw.WriteInt(w, 128) // if protoVers >= 21 from Java client OSBTreeBonsaiRemote#fetchEntriesMajor
-	}, func(r io.Reader) {
-		linkEntryBytes = rw.ReadBytes(r)
 	})
-	if err != nil {
-		return
+	return entries, err
+}
+
+// readRidBagEntries reads a list of tree entries in a form of (count:int)[(link:rid)(counter:int)]*
+func readRidBagEntries(r *rw.Reader) ([]orient.RidBagEntry, error) {
+	n := int(r.ReadInt())
+	if err := r.Err(); err != nil {
+		return nil, err
 	}
-	r := bytes.NewReader(linkEntryBytes)
-	n := int(rw.ReadInt(r))
-	var lnk orient.OIdentifiable
-	for i := 0; i < n; i++ { // loop over all the serialized links
-		lnk, err = linkSerde.DeserializeLink(r)
-		if err != nil {
-			return err
+	entries := make([]orient.RidBagEntry, n)
+	for i := range entries {
+		if err := entries[i].RID.FromStream(r); err != nil {
+			return nil, err
 		}
-		linkBag.AddLink(lnk)
+		entries[i].Count = int(r.ReadInt())
+	}
+	return entries, r.Err()
+}
 
-		// FIXME: for some reason the server returns a serialized link
-		//        followed by an integer (so far always a 1 in my expts).
-		//        Not sure what to do with this int, so ignore for now
-		intval := rw.ReadInt(r)
+// readCollectionChanges reads a list of tree-based RidBags created or moved by the server, keyed by RidBag UUID:
+//
+//	(count:int)[(uuid-most-sig-bits:long)(uuid-least-sig-bits:long)(updated-file-id:long)(updated-page-index:long)(updated-page-offset:int)]*
+func readCollectionChanges(r *rw.Reader) map[uuid.UUID]orient.BonsaiPointer {
+	n := int(r.ReadInt())
+	if n <= 0 {
+		return nil
+	}
+	ptrs := make(map[uuid.UUID]orient.BonsaiPointer, n)
+	for i := 0; i < n && r.Err() == nil; i++ {
+		var id uuid.UUID
+		binary.BigEndian.PutUint64(id[:8], uint64(r.ReadLong()))
+		binary.BigEndian.PutUint64(id[8:], uint64(r.ReadLong()))
+		ptrs[id] = orient.BonsaiPointer{FileID: r.ReadLong(), PageIndex: r.ReadLong(), PageOffset: int(r.ReadInt())}
+	}
+	return ptrs
+}
 
-		if intval != int32(1) {
-			glog.Warningf("Found a use case where the val pair of a link was not 1: %d", intval)
-		}
+// setRidBagPointers applies pointers of tree-based RidBags returned by the server to a saved record.
+func setRidBagPointers(rec orient.ORecord, ptrs map[uuid.UUID]orient.BonsaiPointer) {
+	if doc, ok := rec.(*orient.Document); ok {
+		doc.SetRidBagPointers(ptrs)
 	}
+}
 
-	return nil
+// RidBagSize sends REQUEST_RIDBAG_GET_SIZE to get the size of tree-based RidBag with pending changes applied.
+func (db *Database) RidBagSize(ptr orient.BonsaiPointer, changes orient.RidBagChanges) (int, error) {
+	return db.RidBagSizeContext(context.Background(), ptr, changes)
 }
 
-// FetchFirstKeyOfRemoteLinkBag is the entry point for retrieving links from
-// a remote server-side side LinkBag.  In general, this method should not be
-// called by end users. Instead, end users should call FetchEntriesOfRemoteLinkBag
-//
-// TODO: make this an unexported func?
-func (db *Database) GetFirstKeyOfRemoteLinkBag(linkBag *orient.OLinkBag) (lnk orient.OIdentifiable, err error) {
-	defer catch(&err)
-
-	var firstKeyBytes []byte
-	err = db.sess.sendCmd(requestSBTREE_BONSAI_FIRST_KEY, func(w io.Writer) {
-		writeLinkBagCollectionPointer(w, linkBag)
-	}, func(r io.Reader) {
-		firstKeyBytes = rw.ReadBytes(r)
-	})
-	if err != nil {
-		return
-	}
-	r := bytes.NewReader(firstKeyBytes)
-	typeByte := rw.ReadByte(r)
-	if typeByte != binserde.LinkSerializer {
-		err = fmt.Errorf("GetFirstKeyOfRemoteLinkBag: unknown entry type: %d", typeByte)
-		return
-	}
-	return binserde.OLinkSerializer{}.DeserializeLink(r)
-}
-
-func writeLinkBagCollectionPointer(w io.Writer, linkBag *orient.OLinkBag) {
-	// (treePointer:collectionPointer)(changes)
-	// where collectionPtr = (fileId:long)(pageIndex:long)(pageOffset:int)
-	rw.WriteLong(w, linkBag.GetFileID())
-	rw.WriteLong(w, linkBag.GetPageIndex())
-	rw.WriteInt(w, linkBag.GetPageOffset())
-}
-
-// Large LinkBags (aka RidBags) are stored on the server. To look up their
-// size requires a call to the database.  The size is returned.  Note that the
-// Size field of the linkBag is NOT updated.  That is left for the caller to
-// decide whether to do.
-func (db *Database) GetSizeOfRemoteLinkBag(linkBag *orient.OLinkBag) (val int, err error) {
-	err = db.sess.sendCmd(requestRIDBAG_GET_SIZE, func(w io.Writer) {
-		writeLinkBagCollectionPointer(w, linkBag)
-		rw.WriteBytes(w, []byte{0, 0, 0, 0}) // changes => TODO: right now not supporting any change -> just writing empty changes
-	}, func(r io.Reader) {
-		val = int(rw.ReadInt(r))
+// RidBagSizeContext is like RidBagSize, but allows to cancel the request with a context.
+func (db *Database) RidBagSizeContext(ctx context.Context, ptr orient.BonsaiPointer, changes orient.RidBagChanges) (int, error) {
+	var n int
+	err := db.sess.sendCmdContext(ctx, requestRIDBAG_GET_SIZE, func(w *rw.Writer) error {
+		if err := ptr.ToStream(w); err != nil {
+			return err
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := changes.ToStream(buf); err != nil {
+			return err
+		}
+		w.WriteBytes(buf.Bytes())
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		n = int(r.ReadInt())
+		return r.Err()
 	})
-	return
+	return n, err
 }
-*/
 
-// ResolveLinks iterates over all the OLinks passed in and does a
-// FetchRecordByRID for each one that has a null Record.
-// TODO: maybe include a fetchplan here?
-// TODO: remove it from obinary
+// ResolveLinks replaces links that have no records with records loaded from database or records cache.
+// Links to records that do not exist are left untouched.
 func (db *Database) ResolveLinks(links []orient.OIdentifiable) error {
-	fetchPlan := orient.FetchPlan("")
-	for i := 0; i < len(links); i++ {
-		if links[i].GetRecord() == nil {
-			rec, err := db.GetRecordByRID(links[i].GetIdentity(), fetchPlan, true)
-			if err != nil {
-				return err
-			}
-			panic(fmt.Errorf("resolve links record type: %T", rec))
-			/*
-				docs, err := recs.AsDocuments()
-				if err != nil {
-					return err
-				} else if len(docs) != 1 {
-					glog.Warningf("More than one record returned from GetRecordByRID. Please report this use case!")
-				}
-				links[i].Record = docs[0]*/
+	return db.ResolveLinksContext(context.Background(), links)
+}
+
+// ResolveLinksContext is like ResolveLinks, but allows to cancel the request with a context.
+func (db *Database) ResolveLinksContext(ctx context.Context, links []orient.OIdentifiable) error {
+	for i, l := range links {
+		if l == nil || l.GetRecord() != nil {
+			continue
+		}
+		rec, err := db.GetRecordByRIDContext(ctx, l.GetIdentity(), orient.DefaultFetchPlan, false)
+		if err != nil {
+			return err
+		} else if rec != nil {
+			links[i] = rec
 		}
 	}
 	return nil
 }
 
 // ClustersCount gets the number of records in all the clusters specified.
-func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (val int64, err error) {
+func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (int64, error) {
+	return db.ClustersCountContext(context.Background(), withDeleted, clusterNames...)
+}
+
+// ClustersCountContext is like ClustersCount, but allows to cancel the request with a context.
+func (db *Database) ClustersCountContext(ctx context.Context, withDeleted bool, clusterNames ...string) (int64, error) {
 	clusterIDs := make([]int16, len(clusterNames))
 	for i, name := range clusterNames {
 		clusterID, err := db.findClusterWithName(name)
@@ -534,7 +879,8 @@ func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (val
 		}
 		clusterIDs[i] = clusterID
 	}
-	err = db.sess.sendCmd(requestDataClusterCOUNT, func(w *rw.Writer) error {
+	var val int64
+	err := db.sess.sendCmdContext(ctx, requestDataClusterCOUNT, func(w *rw.Writer) error {
 		w.WriteShort(int16(len(clusterIDs)))
 		for _, id := range clusterIDs {
 			w.WriteShort(id)
@@ -545,41 +891,98 @@ func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (val
 		val = r.ReadLong()
 		return r.Err()
 	})
-	return
+	if err != nil {
+		return 0, err
+	}
+	return val, nil
 }
 
-func (db *Database) getLongFromDB(cmd byte) (val int64, err error) {
-	val = -1
-	err = db.sess.sendCmd(cmd, nil, func(r *rw.Reader) error {
+func (db *Database) getLongFromDB(ctx context.Context, cmd byte) (int64, error) {
+	var val int64
+	err := db.sess.sendCmdContext(ctx, cmd, nil, func(r *rw.Reader) error {
 		val = r.ReadLong()
 		return r.Err()
 	})
-	return
+	if err != nil {
+		return -1, err
+	}
+	return val, nil
+}
+
+// ClusterPositions returns a chunk of positions of existing records in a cluster, relative to a given position.
+// It sends one of REQUEST_POSITIONS_HIGHER, REQUEST_POSITIONS_LOWER, REQUEST_POSITIONS_CEILING or REQUEST_POSITIONS_FLOOR.
+func (db *Database) ClusterPositions(clusterName string, pos int64, op orient.PositionsOp) ([]orient.PhysicalPosition, error) {
+	return db.ClusterPositionsContext(context.Background(), clusterName, pos, op)
+}
+
+// ClusterPositionsContext is like ClusterPositions, but allows to cancel the request with a context.
+func (db *Database) ClusterPositionsContext(ctx context.Context, clusterName string, pos int64, op orient.PositionsOp) ([]orient.PhysicalPosition, error) {
+	var cmd byte
+	switch op {
+	case orient.PositionsHigher:
+		cmd = requestPositionsHIGHER
+	case orient.PositionsLower:
+		cmd = requestPositionsLOWER
+	case orient.PositionsCeiling:
+		cmd = requestPositionsCEILING
+	case orient.PositionsFloor:
+		cmd = requestPositionsFLOOR
+	default:
+		return nil, fmt.Errorf("unsupported positions operation: %v", op)
+	}
+	clusterID, err := db.findClusterWithName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	var out []orient.PhysicalPosition
+	err = db.sess.sendCmdContext(ctx, cmd, func(w *rw.Writer) error {
+		w.WriteInt(int32(clusterID))
+		w.WriteLong(pos)
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		n := int(r.ReadInt())
+		if err := r.Err(); err != nil {
+			return err
+		}
+		out = make([]orient.PhysicalPosition, n)
+		for i := range out {
+			out[i].RID = orient.RID{ClusterID: clusterID, ClusterPos: r.ReadLong()}
+			out[i].RecordSize = int(r.ReadInt())
+			out[i].RecordVersion = int(r.ReadInt())
+		}
+		return r.Err()
+	})
+	return out, err
 }
 
-// Returns negative number if no cluster with `name` is found in the clusters slice.
+// findClusterWithName returns an id of the cluster from the cached list of clusters.
+// The list is reloaded once if cluster is not found, since it might be added by another session.
 func (db *Database) findClusterWithName(name string) (int16, error) {
 	name = strings.ToLower(name)
-	var id int16 = -1
-	for _, cluster := range db.db.Clusters {
-		if cluster.Name == name {
-			return cluster.Id, nil
+	for reload := false; ; reload = true {
+		for _, cluster := range db.db.Clusters {
+			if cluster.Name == name {
+				return cluster.Id, nil
+			}
+		}
+		if reload {
+			break
+		} else if err := db.reloadClusters(context.Background()); err != nil {
+			return -1, err
 		}
 	}
-	if id < 0 {
-		// TODO: This is problematic - someone else may add the cluster not through this
-		//       driver session and then this would fail - so options:
-		//       1) do a lookup of all clusters on the DB
-		//       2) provide a FetchClusterCountById(dbc, clusterID)
-		return id, fmt.Errorf("fixme: No cluster with name %s is known in database %s", name, db.db.Name)
-	}
-	return id, nil
+	return -1, fmt.Errorf("No cluster with name %s is known in database %s", name, db.db.Name)
 }
 
 // Use this to create a new record in the OrientDB database
 // you are currently connected to.
 // Does REQUEST_RECORD_CREATE OrientDB cmd (binary network protocol).
 func (db *Database) CreateRecord(rec orient.ORecord) error {
+	return db.CreateRecordContext(context.Background(), rec)
+}
+
+// CreateRecordContext is like CreateRecord, but allows to cancel the request with a context.
+func (db *Database) CreateRecordContext(ctx context.Context, rec orient.ORecord) error {
 	clusterID := int16(-1) // indicates new class/cluster
 	switch r := rec.(type) {
 	case *orient.Document:
@@ -600,34 +1003,42 @@ func (db *Database) CreateRecord(rec orient.ORecord) error {
 	var (
 		rid  orient.RID
 		vers int
+		ptrs map[uuid.UUID]orient.BonsaiPointer
 	)
 
-	if err = db.sess.sendCmd(requestRecordCREATE, func(w *rw.Writer) error {
+	if err = db.sess.sendCmdContext(ctx, requestRecordCREATE, func(w *rw.Writer) error {
 		w.WriteShort(clusterID)
 		w.WriteBytes(content)
 		w.WriteByte(byte(rec.RecordType())) // document record-type
 		w.WriteByte(byte(0))                // synchronous mode indicator
 		return w.Err()
 	}, func(r *rw.Reader) error {
-		if err = rid.FromStream(r); err != nil {
+		if err := rid.FromStream(r); err != nil {
 			return err
 		}
 		vers = int(r.ReadInt())
-		nCollChanges := r.ReadInt()
-		if nCollChanges != 0 {
-			panic("CreateRecord: Found case where number-collection-changes is not zero -> log case and impl code to handle")
-		}
+		ptrs = readCollectionChanges(r)
 		return r.Err()
 	}); err != nil {
 		return err
 	}
 	// In the Java client, they now a 'select from XXX' at this point -> would that be useful here?
-	return rec.Fill(rid, vers, content)
+	setRidBagPointers(rec, ptrs)
+	if err = rec.Fill(rid, vers, content); err != nil {
+		return err
+	}
+	db.updateCachedRecord(rec)
+	return nil
 }
 
 // UpdateRecord should be used update an existing record in the OrientDB database.
 // It does the REQUEST_RECORD_UPDATE OrientDB cmd (network binary protocol)
 func (db *Database) UpdateRecord(rec orient.ORecord) error {
+	return db.UpdateRecordContext(context.Background(), rec)
+}
+
+// UpdateRecordContext is like UpdateRecord, but allows to cancel the request with a context.
+func (db *Database) UpdateRecordContext(ctx context.Context, rec orient.ORecord) error {
 	if rec == nil {
 		return fmt.Errorf("record is nil")
 	} else if !rec.GetIdentity().IsPersistent() {
@@ -637,8 +1048,11 @@ func (db *Database) UpdateRecord(rec orient.ORecord) error {
 	if err != nil {
 		return err
 	}
-	var vers = rec.Version()
-	if err = db.sess.sendCmd(requestRecordUPDATE, func(w *rw.Writer) error {
+	var (
+		vers = rec.Version()
+		ptrs map[uuid.UUID]orient.BonsaiPointer
+	)
+	if err = db.sess.sendCmdContext(ctx, requestRecordUPDATE, func(w *rw.Writer) error {
 		if err := rec.GetIdentity().ToStream(w); err != nil {
 			return err
 		}
@@ -652,15 +1066,15 @@ func (db *Database) UpdateRecord(rec orient.ORecord) error {
 		return w.Err()
 	}, func(r *rw.Reader) error {
 		vers = int(r.ReadInt())
-		nCollChanges := r.ReadInt()
-		if nCollChanges != 0 {
-			// if > 0, then have to deal with RidBag mgmt:
-			// [(uuid-most-sig-bits:long)(uuid-least-sig-bits:long)(updated-file-id:long)(updated-page-index:long)(updated-page-offset:int)]
-			panic("CreateRecord: Found case where number-collection-changes is not zero -> log case and impl code to handle")
-		}
+		ptrs = readCollectionChanges(r)
 		return r.Err()
 	}); err != nil {
 		return err
 	}
-	return rec.Fill(rec.GetIdentity(), vers, content)
+	setRidBagPointers(rec, ptrs)
+	if err = rec.Fill(rec.GetIdentity(), vers, content); err != nil {
+		return err
+	}
+	db.updateCachedRecord(rec)
+	return nil
 }
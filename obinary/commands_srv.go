@@ -1,6 +1,7 @@
 package obinary
 
 import (
+	"context"
 	"io"
 
 	"gopkg.in/istreamdata/orientgo.v2"
@@ -8,7 +9,8 @@ import (
 )
 
 type Manager struct {
-	sess *session
+	sess       *session
+	user, pass string // credentials are required for shutdown request
 }
 
 /// In the Java client the "server command" functionality is encapsulated
@@ -22,16 +24,17 @@ type Manager struct {
 // session before any other server-level commands. The username and password
 // required are for the server (admin) not any particular database.
 func (c *Client) ConnectToServer(adminUser, adminPassw string) (mgr *Manager, err error) {
+	return c.ConnectToServerContext(context.Background(), adminUser, adminPassw)
+}
+
+// ConnectToServerContext is like ConnectToServer, but allows to cancel the request with a context.
+func (c *Client) ConnectToServerContext(ctx context.Context, adminUser, adminPassw string) (*Manager, error) {
 	var (
 		sessId int32
 		//token []byte
 	)
-	err = c.root.sendCmd(requestConnect, func(w *rw.Writer) error {
-		w.WriteStrings(driverName, driverVersion)
-		w.WriteShort(int16(c.curProtoVers))
-		w.WriteNull() // dbclient id - only for cluster config // TODO: change to use dbc.clusteredConfig once that is added
-		w.WriteString(c.recordFormat.String())
-		w.WriteBool(false) // use token (true) or session (false)
+	err := c.root.sendCmdContext(ctx, requestConnect, func(w *rw.Writer) error {
+		c.sendClientInfo(w, false)
 		w.WriteStrings(adminUser, adminPassw)
 		return w.Err()
 	}, func(r *rw.Reader) error {
@@ -40,22 +43,34 @@ func (c *Client) ConnectToServer(adminUser, adminPassw string) (mgr *Manager, er
 		return r.Err()
 	})
 	if err != nil {
-		return
+		return nil, err
 	}
-	mgr = &Manager{sess: c.newSess(sessId)}
-	return
+	return &Manager{sess: c.newSess(sessId), user: adminUser, pass: adminPassw}, nil
 }
 
 func (c *Client) Auth(adminUser, adminPassw string) (orient.DBAdmin, error) {
 	return c.ConnectToServer(adminUser, adminPassw)
 }
 
+func (c *Client) AuthContext(ctx context.Context, adminUser, adminPassw string) (orient.DBAdmin, error) {
+	return c.ConnectToServerContext(ctx, adminUser, adminPassw)
+}
+
 // CreateDatabase will create a `remote` database of the type and storageType specified.
 // dbType must be type DocumentDBType or GraphDBType.
 // storageType must type PersistentStorageType or VolatileStorageType.
 func (m *Manager) CreateDatabase(dbname string, dbtype orient.DatabaseType, storageType orient.StorageType) error {
-	return m.sess.sendCmd(requestDbCreate, func(w *rw.Writer) error {
-		return w.WriteStrings(dbname, string(dbtype), string(storageType))
+	return m.CreateDatabaseContext(context.Background(), dbname, dbtype, storageType)
+}
+
+// CreateDatabaseContext is like CreateDatabase, but allows to cancel the request with a context.
+func (m *Manager) CreateDatabaseContext(ctx context.Context, dbname string, dbtype orient.DatabaseType, storageType orient.StorageType) error {
+	return m.sess.sendCmdContext(ctx, requestDbCreate, func(w *rw.Writer) error {
+		w.WriteStrings(dbname, string(dbtype), string(storageType))
+		if m.sess.cli.curProtoVers > ProtoVersion35 {
+			w.WriteNull() // backup path to restore database from
+		}
+		return w.Err()
 	}, nil)
 }
 
@@ -68,7 +83,12 @@ func (m *Manager) CreateDatabase(dbname string, dbtype orient.DatabaseType, stor
 // This is a "server" command, so you must have already called
 // ConnectToServer before calling this function.
 func (m *Manager) DropDatabase(dbname string, dbtype orient.StorageType) (err error) {
-	return m.sess.sendCmd(requestDbDrop, func(w *rw.Writer) error {
+	return m.DropDatabaseContext(context.Background(), dbname, dbtype)
+}
+
+// DropDatabaseContext is like DropDatabase, but allows to cancel the request with a context.
+func (m *Manager) DropDatabaseContext(ctx context.Context, dbname string, dbtype orient.StorageType) error {
+	return m.sess.sendCmdContext(ctx, requestDbDrop, func(w *rw.Writer) error {
 		return w.WriteStrings(dbname, string(dbtype))
 	}, nil)
 }
@@ -77,13 +97,22 @@ func (m *Manager) DropDatabase(dbname string, dbtype orient.StorageType) (err er
 // ConnectToServer, otherwise an authorization error will be returned.
 // The storageType param must be either PersistentStorageType or VolatileStorageType.
 func (m *Manager) DatabaseExists(dbname string, storageType orient.StorageType) (val bool, err error) {
-	err = m.sess.sendCmd(requestDbExists, func(w *rw.Writer) error {
+	return m.DatabaseExistsContext(context.Background(), dbname, storageType)
+}
+
+// DatabaseExistsContext is like DatabaseExists, but allows to cancel the request with a context.
+func (m *Manager) DatabaseExistsContext(ctx context.Context, dbname string, storageType orient.StorageType) (bool, error) {
+	var val bool
+	err := m.sess.sendCmdContext(ctx, requestDbExists, func(w *rw.Writer) error {
 		return w.WriteStrings(dbname, string(storageType))
 	}, func(r *rw.Reader) error {
 		val = r.ReadBool()
 		return r.Err()
 	})
-	return
+	if err != nil {
+		return false, err
+	}
+	return val, nil
 }
 
 // RequestDBList works like the "list databases" command from the OrientDB client.
@@ -93,8 +122,13 @@ func (m *Manager) DatabaseExists(dbname string, storageType orient.StorageType)
 //     key:  cars
 //     val:  plocal:/path/to/orientdb-community-2.0.1/databases/cars
 func (m *Manager) ListDatabases() (list map[string]string, err error) {
+	return m.ListDatabasesContext(context.Background())
+}
+
+// ListDatabasesContext is like ListDatabases, but allows to cancel the request with a context.
+func (m *Manager) ListDatabasesContext(ctx context.Context) (list map[string]string, err error) {
 	var data []byte
-	err = m.sess.sendCmd(requestDbLIST, nil, func(r *rw.Reader) error {
+	err = m.sess.sendCmdContext(ctx, requestDbLIST, nil, func(r *rw.Reader) error {
 		data = r.ReadBytes()
 		return r.Err()
 	})
@@ -120,6 +154,130 @@ func (m *Manager) ListDatabases() (list map[string]string, err error) {
 	return
 }
 
+// ServerConfig returns all server configuration values (REQUEST_CONFIG_LIST).
+func (m *Manager) ServerConfig() (map[string]string, error) {
+	return m.ServerConfigContext(context.Background())
+}
+
+// ServerConfigContext is like ServerConfig, but allows to cancel the request with a context.
+func (m *Manager) ServerConfigContext(ctx context.Context) (map[string]string, error) {
+	var conf map[string]string
+	err := m.sess.sendCmdContext(ctx, requestConfigLIST, nil, func(r *rw.Reader) error {
+		n := int(r.ReadShort())
+		conf = make(map[string]string, n)
+		for i := 0; i < n; i++ {
+			k := r.ReadString()
+			conf[k] = r.ReadString()
+		}
+		return r.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// ServerConfigValue returns a single server configuration value (REQUEST_CONFIG_GET).
+func (m *Manager) ServerConfigValue(key string) (string, error) {
+	return m.ServerConfigValueContext(context.Background(), key)
+}
+
+// ServerConfigValueContext is like ServerConfigValue, but allows to cancel the request with a context.
+func (m *Manager) ServerConfigValueContext(ctx context.Context, key string) (string, error) {
+	var val string
+	err := m.sess.sendCmdContext(ctx, requestConfigGET, func(w *rw.Writer) error {
+		w.WriteString(key)
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		val = r.ReadString()
+		return r.Err()
+	})
+	return val, err
+}
+
+// SetServerConfig changes a server configuration value (REQUEST_CONFIG_SET).
+func (m *Manager) SetServerConfig(key, val string) error {
+	return m.SetServerConfigContext(context.Background(), key, val)
+}
+
+// SetServerConfigContext is like SetServerConfig, but allows to cancel the request with a context.
+func (m *Manager) SetServerConfigContext(ctx context.Context, key, val string) error {
+	return m.sess.sendCmdContext(ctx, requestConfigSET, func(w *rw.Writer) error {
+		return w.WriteStrings(key, val)
+	}, nil)
+}
+
+// Shutdown stops the server. Credentials used for ConnectToServer are sent with the request.
+func (m *Manager) Shutdown() error {
+	return m.ShutdownContext(context.Background())
+}
+
+// ShutdownContext is like Shutdown, but allows to cancel the request with a context.
+func (m *Manager) ShutdownContext(ctx context.Context) error {
+	err := m.sess.sendCmdContext(ctx, requestShutdown, func(w *rw.Writer) error {
+		return w.WriteStrings(m.user, m.pass)
+	}, nil)
+	if err == ErrClosedConnection || err == io.EOF {
+		// server may close the connection before the response is read
+		err = nil
+	}
+	return err
+}
+
+// FreezeDatabase flushes all database data to disk and blocks write operations until
+// ReleaseDatabase is called, allowing to make a consistent copy of database files.
+func (m *Manager) FreezeDatabase(dbname string, storageType orient.StorageType) error {
+	return m.FreezeDatabaseContext(context.Background(), dbname, storageType)
+}
+
+// FreezeDatabaseContext is like FreezeDatabase, but allows to cancel the request with a context.
+func (m *Manager) FreezeDatabaseContext(ctx context.Context, dbname string, storageType orient.StorageType) error {
+	return m.sess.sendCmdContext(ctx, requestDbFREEZE, func(w *rw.Writer) error {
+		return w.WriteStrings(dbname, string(storageType))
+	}, nil)
+}
+
+// ReleaseDatabase allows write operations on a database previously frozen with FreezeDatabase.
+func (m *Manager) ReleaseDatabase(dbname string, storageType orient.StorageType) error {
+	return m.ReleaseDatabaseContext(context.Background(), dbname, storageType)
+}
+
+// ReleaseDatabaseContext is like ReleaseDatabase, but allows to cancel the request with a context.
+func (m *Manager) ReleaseDatabaseContext(ctx context.Context, dbname string, storageType orient.StorageType) error {
+	return m.sess.sendCmdContext(ctx, requestDbRELEASE, func(w *rw.Writer) error {
+		return w.WriteStrings(dbname, string(storageType))
+	}, nil)
+}
+
+// FreezeCluster blocks write operations on a single cluster of the database until ReleaseCluster is called.
+func (m *Manager) FreezeCluster(dbname string, clusterID int16, storageType orient.StorageType) error {
+	return m.FreezeClusterContext(context.Background(), dbname, clusterID, storageType)
+}
+
+// FreezeClusterContext is like FreezeCluster, but allows to cancel the request with a context.
+func (m *Manager) FreezeClusterContext(ctx context.Context, dbname string, clusterID int16, storageType orient.StorageType) error {
+	return m.sendClusterCmd(ctx, requestDataClusterFREEZE, dbname, clusterID, storageType)
+}
+
+// ReleaseCluster allows write operations on a cluster previously frozen with FreezeCluster.
+func (m *Manager) ReleaseCluster(dbname string, clusterID int16, storageType orient.StorageType) error {
+	return m.ReleaseClusterContext(context.Background(), dbname, clusterID, storageType)
+}
+
+// ReleaseClusterContext is like ReleaseCluster, but allows to cancel the request with a context.
+func (m *Manager) ReleaseClusterContext(ctx context.Context, dbname string, clusterID int16, storageType orient.StorageType) error {
+	return m.sendClusterCmd(ctx, requestDataClusterRELEASE, dbname, clusterID, storageType)
+}
+
+func (m *Manager) sendClusterCmd(ctx context.Context, op byte, dbname string, clusterID int16, storageType orient.StorageType) error {
+	return m.sess.sendCmdContext(ctx, op, func(w *rw.Writer) error {
+		w.WriteString(dbname)
+		w.WriteShort(clusterID)
+		w.WriteString(string(storageType))
+		return w.Err()
+	}, nil)
+}
+
 func (m *Manager) Close() error {
 	// TODO: what can we do?
 	return m.sess.cli.Close()
@@ -1,12 +1,14 @@
 package obinary
 
+import "time"
+
 // constants specific to the Network Binary Protocol
 
 // internal client constants
 const (
 	noSessionId                = -1
-	MaxProtocolVersion         = 32 // max protocol supported by this client
-	CurrentProtoVersion        = 28
+	MaxProtocolVersion         = 36 // max protocol supported by this client
+	CurrentProtoVersion        = 36 // 37 requires a new DB_OPEN and handshake, which are not implemented yet
 	MinProtocolVersion         = 28 // min protocol supported by this client
 	minBinarySerializerVersion = 22 // if server protocol version is less, use csv serde, not binary serde
 	driverName                 = "OrientDB Go client"
@@ -15,6 +17,12 @@ const (
 	serializeTypeCsv           = "ORecordDocument2csv"     // do not change: required by server
 )
 
+// default timeouts, used if not set in options
+const (
+	defaultDialTimeout      = time.Minute
+	defaultHandshakeTimeout = time.Second * 5
+)
+
 const (
 	// binary protocol sentinel values when reading single records
 	RecordNull = -2
@@ -63,6 +71,7 @@ const (
 	requestDbRELOAD                      = 73 // SINCE 1.0rc4
 	requestDbLIST                        = 74 // SINCE 1.0rc6
 	requestPushDistribConfig             = 80
+	requestPushLiveQuery                 = 81 // SINCE 2.1
 	// DISTRIBUTED
 	requestDbCOPY      = 90 // SINCE 1.0rc8
 	requestREPLICATION = 91 // SINCE 1.0
@@ -104,4 +113,8 @@ const (
 	ProtoVersion30 = 30
 	ProtoVersion31 = 31
 	ProtoVersion32 = 32
+	ProtoVersion33 = 33
+	ProtoVersion34 = 34
+	ProtoVersion35 = 35
+	ProtoVersion36 = 36
 )
@@ -1,10 +1,13 @@
 package obinary
 
 import (
-	"gopkg.in/istreamdata/orientgo.v2"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"gopkg.in/istreamdata/orientgo.v2"
 )
 
 type ODatabase struct {
@@ -53,11 +56,9 @@ func NewDatabase(name string, dbtype orient.DatabaseType) *ODatabase {
 	}
 }
 
-// OStorageConfiguration holds (some of) the information in the "Config Record"
-// #0:0.  At this time, I'm throwing away a lot of the info in record #0:0
-// until proven that the ogonori client needs them.
+// OStorageConfiguration holds the information in the "Config Record" #0:0.
 type OStorageConfiguration struct {
-	version       byte // TODO: of what? (=14 for OrientDB 2.1)
+	version       byte // (=14 for OrientDB 2.1)
 	name          string
 	schemaRID     orient.RID // usually #0:1
 	dictionaryRID string
@@ -67,13 +68,32 @@ type OStorageConfiguration struct {
 	dateFmt       string
 	dateTimeFmt   string
 	timezone      string
+	charset       string
+	clusters      []orient.ClusterInfo // nil if cluster list cannot be parsed
+}
+
+// location returns database time zone, or nil if it's not set or is unknown.
+func (sc *OStorageConfiguration) location() *time.Location {
+	if sc.timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(sc.timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
 }
 
 // parseConfigRecord takes the pipe-separate values that comes back
 // from reading record #0:0 and turns it into an OStorageConfiguration
 // object, which it adds to the db database object.
-func (sc *OStorageConfiguration) parse(psvData string) error {
+//
+// Layout of the record depends on protocol version of the client (see OStorageConfiguration.toStream in Java).
+func (sc *OStorageConfiguration) parse(psvData string, protoVers int) error {
 	toks := strings.Split(psvData, "|")
+	if len(toks) < 10 {
+		return fmt.Errorf("config record is too short: %d fields", len(toks))
+	}
 
 	version, err := strconv.ParseInt(toks[0], 10, 8)
 	if err != nil {
@@ -82,18 +102,108 @@ func (sc *OStorageConfiguration) parse(psvData string) error {
 
 	sc.version = byte(version)
 	sc.name = strings.TrimSpace(toks[1])
-	sc.schemaRID = orient.MustParseRID(toks[2])
+	if sc.schemaRID, err = orient.ParseRID(toks[2]); err != nil {
+		return err
+	}
 	sc.dictionaryRID = strings.TrimSpace(toks[3])
-	sc.idxMgrRID = orient.MustParseRID(toks[4])
+	if sc.idxMgrRID, err = orient.ParseRID(toks[4]); err != nil {
+		return err
+	}
 	sc.localeLang = strings.TrimSpace(toks[5])
 	sc.localeCountry = strings.TrimSpace(toks[6])
 	sc.dateFmt = strings.TrimSpace(toks[7])
 	sc.dateTimeFmt = strings.TrimSpace(toks[8])
 	sc.timezone = strings.TrimSpace(toks[9])
-
+	if len(toks) > 10 {
+		sc.charset = strings.TrimSpace(toks[10])
+	}
+	// cluster list is optional, since format of previous fields changed between versions
+	if len(toks) > 11 {
+		sc.clusters = parseConfigClusters(toks[11:], protoVers)
+	}
 	return nil
 }
 
+// configTokens is a reader of pipe-separated config record fields.
+type configTokens struct {
+	toks []string
+	err  bool
+}
+
+func (t *configTokens) next() string {
+	if len(t.toks) == 0 {
+		t.err = true
+		return ""
+	}
+	s := strings.TrimSpace(t.toks[0])
+	t.toks = t.toks[1:]
+	return s
+}
+
+func (t *configTokens) nextInt() int {
+	v, err := strconv.Atoi(t.next())
+	if err != nil {
+		t.err = true
+	}
+	return v
+}
+
+func (t *configTokens) skip(n int) {
+	for i := 0; i < n; i++ {
+		t.next()
+	}
+}
+
+// parseConfigClusters parses a list of clusters from config record fields that follow charset.
+// It returns nil if the list cannot be parsed.
+func parseConfigClusters(toks []string, protoVers int) []orient.ClusterInfo {
+	t := &configTokens{toks: toks}
+	if protoVers > ProtoVersion24 {
+		t.skip(1) // conflict strategy
+	}
+	// file template: max size, file type, start size, max file size, increment size, defrag
+	t.skip(6)
+	t.skip(3 * t.nextInt()) // info files: path, type, max size
+	n := t.nextInt()
+	if t.err || n < 0 {
+		return nil
+	}
+	clusters := make([]orient.ClusterInfo, 0, n)
+	for i := 0; i < n && !t.err; i++ {
+		id := t.nextInt()
+		if id < 0 { // removed cluster
+			continue
+		}
+		c := orient.ClusterInfo{ID: int16(id), Name: t.next()}
+		t.skip(1) // data segment id
+		switch tp := t.next(); tp {
+		case "d":
+			c.Type = "paginated"
+			t.skip(4) // use WAL, record overflow grow factor, record grow factor, compression
+			if protoVers >= ProtoVersion31 {
+				t.skip(1) // encryption
+			}
+			if protoVers > ProtoVersion24 {
+				t.skip(1) // conflict strategy
+			}
+			if protoVers > ProtoVersion25 {
+				c.Status = t.next()
+				if c.Status != "ONLINE" && c.Status != "OFFLINE" {
+					return nil
+				}
+			}
+		default:
+			// other cluster types are not used since OrientDB 2.0
+			return nil
+		}
+		clusters = append(clusters, c)
+	}
+	if t.err {
+		return nil
+	}
+	return clusters
+}
+
 type OCluster struct {
 	Name string
 	Id   int16
@@ -0,0 +1,95 @@
+package obinary_test
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary"
+)
+
+// fakeServer accepts connections and optionally sends protocol version, ignoring any requests after that.
+func fakeServer(t testing.TB, handshake bool) (addr string, closer io.Closer) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if handshake {
+					var vers [2]byte
+					binary.BigEndian.PutUint16(vers[:], uint16(obinary.CurrentProtoVersion))
+					conn.Write(vers[:])
+				}
+				io.Copy(ioutil.Discard, conn)
+			}()
+		}
+	}()
+	return l.Addr().String(), l
+}
+
+func TestDialHandshakeTimeout(t *testing.T) {
+	addr, l := fakeServer(t, false)
+	defer l.Close()
+	start := time.Now()
+	_, err := obinary.DialWithOptions(addr, orient.Options{HandshakeTimeout: time.Millisecond * 50})
+	if err == nil {
+		t.Fatal("expected handshake timeout")
+	} else if dt := time.Since(start); dt > time.Second {
+		t.Fatalf("handshake timeout was not applied: %v", dt)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	addr, l := fakeServer(t, true)
+	defer l.Close()
+	cli, err := obinary.DialWithOptions(addr, orient.Options{ReadTimeout: time.Millisecond * 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	if _, err = cli.ConnectToServer("root", "root"); err != obinary.ErrReadTimeout {
+		t.Fatalf("expected read timeout, got: %v", err)
+	}
+	// connection is closed, so next requests fail instead of waiting for the abandoned response
+	errc := make(chan error, 1)
+	go func() {
+		_, err := cli.ConnectToServer("root", "root")
+		errc <- err
+	}()
+	select {
+	case err = <-errc:
+		if err == nil || err == obinary.ErrReadTimeout {
+			t.Fatalf("expected closed connection, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request is blocked after read timeout")
+	}
+}
+
+func TestDialFailover(t *testing.T) {
+	addr, l := fakeServer(t, true)
+	defer l.Close()
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downAddr := down.Addr().String()
+	down.Close()
+
+	cli, err := obinary.Dial("remote:" + downAddr + ";" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Close()
+}
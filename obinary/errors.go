@@ -13,7 +13,10 @@ func (e ErrUnsupportedVersion) Error() string {
 	return fmt.Sprintf("server protocol version %d is not supported (valid: %d-%d)", int(e), MinProtocolVersion, MaxProtocolVersion)
 }
 
-var ErrClosedConnection = fmt.Errorf("closed connection")
+var ErrClosedConnection = orient.ErrClosedConnection
+
+// ErrReadTimeout is returned when server does not respond to a request within Options.ReadTimeout.
+var ErrReadTimeout = orient.ErrReadTimeout
 
 type ErrBrokenProtocol struct {
 	Reason error
@@ -1,9 +1,51 @@
 package obinary
 
 import (
+	"github.com/nu7hatch/gouuid"
+	"gopkg.in/istreamdata/orientgo.v2"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 )
 
 func ReadErrorResponse(r *rw.Reader) (serverException error) {
 	return readErrorResponse(r, CurrentProtoVersion)
 }
+
+const RequestPushLiveQuery = requestPushLiveQuery
+
+func NewTestClient() *Client {
+	return &Client{recordFormat: orient.GetDefaultRecordSerializer()}
+}
+
+func (c *Client) SetLiveHandler(token int32, fnc func(orient.LiveEvent)) {
+	c.setLiveHandler(token, fnc)
+}
+
+func (c *Client) HandlePush(tp byte, data []byte) error {
+	return c.handlePush(tp, data)
+}
+
+func ParseConfigRecord(data string, protoVers int) (clusters []orient.ClusterInfo, timezone string, err error) {
+	var sc OStorageConfiguration
+	err = sc.parse(data, protoVers)
+	return sc.clusters, sc.timezone, err
+}
+
+func ReadCommandStream(r *rw.Reader, release func()) (orient.CommandStream, error) {
+	cli := NewTestClient()
+	cli.curProtoVers = CurrentProtoVersion
+	s := &commandStream{db: &Database{sess: &session{cli: cli}}, r: r, release: release}
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func ReadCommandResult(r *rw.Reader) (interface{}, error) {
+	cli := NewTestClient()
+	cli.curProtoVers = CurrentProtoVersion
+	return (&Database{sess: &session{cli: cli}}).readSynchResult(r)
+}
+
+func ReadCollectionChanges(r *rw.Reader) map[uuid.UUID]orient.BonsaiPointer {
+	return readCollectionChanges(r)
+}
@@ -0,0 +1,191 @@
+package obinary
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+// push message kinds for live queries
+const (
+	liveRecordPush      = 'r'
+	liveUnsubscribePush = 'u'
+)
+
+func (c *Client) setLiveHandler(token int32, fnc func(orient.LiveEvent)) {
+	c.livemu.Lock()
+	if c.live == nil {
+		c.live = make(map[int32]func(orient.LiveEvent))
+	}
+	c.live[token] = fnc
+	c.livemu.Unlock()
+}
+
+func (c *Client) popLiveHandler(token int32) func(orient.LiveEvent) {
+	c.livemu.Lock()
+	fnc := c.live[token]
+	delete(c.live, token)
+	c.livemu.Unlock()
+	return fnc
+}
+
+func (c *Client) getLiveHandler(token int32) func(orient.LiveEvent) {
+	c.livemu.Lock()
+	defer c.livemu.Unlock()
+	return c.live[token]
+}
+
+// closeLive ends all live query subscriptions of this connection.
+func (c *Client) closeLive() {
+	c.livemu.Lock()
+	live := c.live
+	c.live = nil
+	c.livemu.Unlock()
+	for _, fnc := range live {
+		fnc(orient.LiveEvent{Op: orient.LiveUnsubscribed})
+	}
+}
+
+// handlePush processes a push message from the server.
+func (c *Client) handlePush(tp byte, data []byte) error {
+	switch tp {
+	case requestPushLiveQuery:
+		return c.handleLivePush(data)
+	}
+	return nil // other pushes are not supported and may be safely ignored
+}
+
+func (c *Client) handleLivePush(data []byte) error {
+	r := rw.NewReader(bytes.NewReader(data))
+	switch kind := r.ReadByte(); kind {
+	case liveRecordPush:
+		op := orient.LiveOperation(r.ReadByte())
+		token := r.ReadInt()
+		recType := orient.RecordType(r.ReadByte())
+		version := int(r.ReadInt())
+		var rid orient.RID
+		if err := rid.FromStream(r); err != nil {
+			return err
+		}
+		content := r.ReadBytes()
+		if err := r.Err(); err != nil {
+			return err
+		}
+		fnc := c.getLiveHandler(token)
+		if fnc == nil {
+			return nil
+		} else if orient.GetRecordFactory(recType) == nil {
+			log.Printf("orientgo: unsupported record type in live query: %q", recType)
+			return nil
+		}
+		rec := orient.NewRecordOfType(recType)
+		switch rc := rec.(type) {
+		case *orient.Document:
+			rc.SetSerializer(c.recordFormat)
+		}
+		if err := rec.Fill(rid, version, content); err != nil {
+			log.Printf("orientgo: cannot decode live query record %v: %v", rid, err)
+			return nil
+		}
+		fnc(orient.LiveEvent{Op: op, Record: rec})
+	case liveUnsubscribePush:
+		token := r.ReadInt()
+		if err := r.Err(); err != nil {
+			return err
+		}
+		if fnc := c.popLiveHandler(token); fnc != nil {
+			fnc(orient.LiveEvent{Op: orient.LiveUnsubscribed})
+		}
+	default:
+		if err := r.Err(); err != nil {
+			return err
+		}
+		log.Printf("orientgo: unknown live query push: %q", kind)
+	}
+	return nil
+}
+
+// liveToken extracts live query token from command result.
+func liveToken(result interface{}) (int32, error) {
+	var doc *orient.Document
+	switch rv := result.(type) {
+	case *orient.Document:
+		doc = rv
+	case []orient.OIdentifiable:
+		if len(rv) == 1 {
+			doc, _ = rv[0].(*orient.Document)
+		}
+	}
+	if doc == nil {
+		return 0, fmt.Errorf("unexpected live query result: %T", result)
+	}
+	fld := doc.GetField("token")
+	if fld == nil {
+		return 0, fmt.Errorf("no token returned for live query")
+	}
+	switch tok := fld.Value.(type) {
+	case int32:
+		return tok, nil
+	case int64:
+		return int32(tok), nil
+	case int:
+		return int32(tok), nil
+	}
+	return 0, fmt.Errorf("unexpected live query token type: %T", fld.Value)
+}
+
+// LiveQuery subscribes to changes of records, matching a given live query. Function is called for each event
+// from connection reading goroutine, thus it must not block. The last event of each subscription has
+// LiveUnsubscribed operation.
+func (db *Database) LiveQuery(cmd orient.CustomSerializable, fnc func(orient.LiveEvent)) (token int, err error) {
+	return db.LiveQueryContext(context.Background(), cmd, fnc)
+}
+
+// LiveQueryContext is like LiveQuery, but allows to cancel the request with a context.
+func (db *Database) LiveQueryContext(ctx context.Context, cmd orient.CustomSerializable, fnc func(orient.LiveEvent)) (int, error) {
+	data, err := orient.SerializeAnyStreamable(cmd)
+	if err != nil {
+		return 0, err
+	}
+	var token int32
+	err = db.sess.sendCmdContext(ctx, requestCommand, func(w *rw.Writer) error {
+		w.WriteByte(byte('l'))
+		w.WriteBytes(data)
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		result, err := db.readSynchResult(r)
+		if err != nil {
+			return err
+		}
+		tok, err := liveToken(result)
+		if err != nil {
+			return err
+		}
+		// handler must be set before response is consumed, since events may follow the response immediately
+		db.sess.cli.setLiveHandler(tok, fnc)
+		token = tok
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(token), nil
+}
+
+// LiveUnsubscribe stops live query subscription with a given token.
+func (db *Database) LiveUnsubscribe(token int) error {
+	return db.LiveUnsubscribeContext(context.Background(), token)
+}
+
+// LiveUnsubscribeContext is like LiveUnsubscribe, but allows to cancel the request with a context.
+func (db *Database) LiveUnsubscribeContext(ctx context.Context, token int) error {
+	_, err := db.CommandContext(ctx, orient.NewSQLCommand(fmt.Sprintf("LIVE UNSUBSCRIBE %d", token)))
+	if fnc := db.sess.cli.popLiveHandler(int32(token)); fnc != nil {
+		fnc(orient.LiveEvent{Op: orient.LiveUnsubscribed})
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package obinary_test
+
+import (
+	"bytes"
+	"testing"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+func TestLivePush(t *testing.T) {
+	cli := obinary.NewTestClient()
+	var events []orient.LiveEvent
+	cli.SetLiveHandler(7, func(ev orient.LiveEvent) {
+		events = append(events, ev)
+	})
+
+	var buf bytes.Buffer
+	w := rw.NewWriter(&buf)
+	w.WriteByte('r')
+	w.WriteByte(byte(orient.LiveCreated))
+	w.WriteInt(7)
+	w.WriteByte(byte(orient.RecordTypeBytes))
+	w.WriteInt(1)
+	w.WriteShort(9)
+	w.WriteLong(3)
+	w.WriteBytes([]byte("data"))
+	if err := cli.HandlePush(obinary.RequestPushLiveQuery, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	w.WriteByte('u')
+	w.WriteInt(7)
+	if err := cli.HandlePush(obinary.RequestPushLiveQuery, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Op != orient.LiveCreated {
+		t.Fatalf("unexpected operation: %v", ev.Op)
+	}
+	rec, ok := ev.Record.(*orient.BytesRecord)
+	if !ok {
+		t.Fatalf("unexpected record type: %T", ev.Record)
+	}
+	if rid := rec.GetIdentity(); rid != (orient.RID{ClusterID: 9, ClusterPos: 3}) || rec.Version() != 1 {
+		t.Fatalf("unexpected record: %v v%d", rid, rec.Version())
+	}
+	if data, _ := rec.Content(); string(data) != "data" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+	if events[1].Op != orient.LiveUnsubscribed {
+		t.Fatalf("expected unsubscribe event, got: %v", events[1].Op)
+	}
+}
@@ -3,6 +3,7 @@ package obinary_test
 import (
 	"bytes"
 	"fmt"
+	"github.com/nu7hatch/gouuid"
 	"gopkg.in/istreamdata/orientgo.v2"
 	"gopkg.in/istreamdata/orientgo.v2/obinary"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
@@ -41,6 +42,7 @@ func TestReadErrorResponseWithSingleException(t *testing.T) {
 
 	equals(t, "org.foo.BlargException", e.Exceptions[0].ExcClass())
 	equals(t, "wibble wibble!!", e.Exceptions[0].ExcMessage())
+	equals(t, "this is a stacktrace simulator\nEOL", string(e.Exceptions[0].(orient.StackException).ExcStack()))
 }
 
 func TestReadErrorResponseWithMultipleExceptions(t *testing.T) {
@@ -67,3 +69,55 @@ func TestReadErrorResponseWithMultipleExceptions(t *testing.T) {
 	equals(t, "org.foo.WobbleException", e.Exceptions[2].ExcClass())
 	equals(t, "Orbital decay", e.Exceptions[2].ExcMessage())
 }
+
+func TestReadCollectionChanges(t *testing.T) {
+	buf := new(bytes.Buffer)
+	bw := rw.NewWriter(buf)
+	bw.WriteInt(1)
+	bw.WriteLong(0x0102030405060708)
+	bw.WriteLong(0x090a0b0c0d0e0f10)
+	orient.BonsaiPointer{FileID: 5, PageIndex: 6, PageOffset: 7}.ToStream(bw)
+
+	ptrs := obinary.ReadCollectionChanges(rw.NewReader(buf))
+	id := uuid.UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	equals(t, map[uuid.UUID]orient.BonsaiPointer{id: {FileID: 5, PageIndex: 6, PageOffset: 7}}, ptrs)
+	equals(t, 0, buf.Len())
+}
+
+func TestParseConfigRecord(t *testing.T) {
+	const data = "14|db|#0:1||#0:2|en|US|yyyy-MM-dd|yyyy-MM-dd HH:mm:ss|Europe/Kiev|UTF-8|version|" +
+		"0|mmap|500Kb|500Mb|50%|auto|0|" + // file template
+		"3|0|internal|-1|d|true|2.0|1.2|nothing| |version|ONLINE|" +
+		"-1|" + // dropped cluster
+		"9|person|-1|d|true|2.0|1.2|nothing| |version|OFFLINE|" +
+		"0|2|round-robin|2|ORecordSerializerBinary|0|0|"
+	clusters, tz, err := obinary.ParseConfigRecord(data, obinary.ProtoVersion31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "Europe/Kiev", tz)
+	equals(t, []orient.ClusterInfo{
+		{ID: 0, Name: "internal", Type: "paginated", Status: "ONLINE"},
+		{ID: 9, Name: "person", Type: "paginated", Status: "OFFLINE"},
+	}, clusters)
+
+	// unknown layout should not break the rest of config
+	clusters, tz, err = obinary.ParseConfigRecord("14|db|#0:1||#0:2|en|US|yyyy-MM-dd|yyyy-MM-dd HH:mm:ss|UTC|UTF-8", obinary.ProtoVersion31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "UTC", tz)
+	if clusters != nil {
+		t.Fatalf("unexpected clusters: %v", clusters)
+	}
+
+	// charset is optional too
+	clusters, tz, err = obinary.ParseConfigRecord("14|db|#0:1||#0:2|en|US|yyyy-MM-dd|yyyy-MM-dd HH:mm:ss|UTC", obinary.ProtoVersion31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, "UTC", tz)
+	if clusters != nil {
+		t.Fatalf("unexpected clusters: %v", clusters)
+	}
+}
@@ -0,0 +1,183 @@
+package obinary
+
+import (
+	"context"
+	"io"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+var _ orient.CommandStream = (*commandStream)(nil)
+
+// CommandStreamContext is like CommandContext, but collections of records are decoded one by one, as they are
+// requested from the returned stream. Session can't be used until stream is closed.
+//
+// Records prefetched by the server are sent after the result, thus links of streamed records are not resolved.
+func (db *Database) CommandStreamContext(ctx context.Context, cmd orient.CustomSerializable) (orient.CommandStream, error) {
+	data, err := orient.SerializeAnyStreamable(cmd)
+	if err != nil {
+		return nil, err
+	}
+	r, release, err := db.sess.sendCmdStream(ctx, requestCommand, func(w *rw.Writer) error {
+		w.WriteByte(byte('s'))
+		w.WriteBytes(data)
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		_, err := db.readSynchResult(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := &commandStream{db: db, r: r, release: release}
+	if err = s.start(); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// commandStream decodes records of a synchronous command result directly from the connection.
+type commandStream struct {
+	db      *Database
+	r       *rw.Reader
+	release func()
+
+	iter bool // records are prefixed with status bytes ('i' result type); otherwise the number of records is known
+	left int  // number of records left to read for 'l' and 's' result types
+
+	value   interface{} // result of a command that returned a single value
+	isValue bool
+
+	err  error
+	done bool // session was released
+}
+
+// start reads result type. Results that are not collections of records are read completely.
+func (s *commandStream) start() (err error) {
+	defer s.db.sess.catch(&err)
+	resType := s.r.ReadByte()
+	if err = s.r.Err(); err != nil {
+		return err
+	}
+	switch resType {
+	case 'l', 's':
+		s.left = int(s.r.ReadInt())
+		return s.r.Err()
+	case 'i':
+		s.iter = true
+		return nil
+	}
+	s.value, err = s.db.readSynchValue(s.r, resType)
+	if err != nil {
+		return err
+	}
+	prefetched, err := s.db.readPrefetched(s.r)
+	if err != nil {
+		return err
+	}
+	resolveLinks(prefetched, s.value)
+	s.isValue = true
+	s.close()
+	return nil
+}
+
+func (s *commandStream) Value() (interface{}, bool) {
+	return s.value, s.isValue
+}
+
+func (s *commandStream) NextContext(ctx context.Context) (orient.OIdentifiable, error) {
+	if s.err != nil {
+		return nil, s.err
+	} else if s.done {
+		return nil, io.EOF
+	}
+	if err := ctx.Err(); err != nil {
+		s.abandon(err)
+		return nil, err
+	}
+	return s.next()
+}
+
+func (s *commandStream) Close() error {
+	for !s.done {
+		s.next()
+	}
+	return s.err
+}
+
+// next reads the next record. Session is released after the last record or on error.
+func (s *commandStream) next() (rec orient.OIdentifiable, err error) {
+	defer func() {
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			s.close()
+		}
+	}()
+	defer s.db.sess.catch(&err)
+	return s.read()
+}
+
+// read reads the next record from the connection. Records prefetched by the server are only stored to cache.
+// After the last record it reads the rest of the response and returns io.EOF.
+func (s *commandStream) read() (orient.OIdentifiable, error) {
+	for {
+		status := byte(1)
+		if s.iter {
+			status = s.r.ReadByte()
+			if err := s.r.Err(); err != nil {
+				return nil, err
+			} else if status <= 0 {
+				return nil, s.readTail()
+			}
+		} else if s.left <= 0 {
+			return nil, s.readTail()
+		} else {
+			s.left--
+		}
+		rec, err := s.db.readIdentifiable(s.r)
+		if err != nil {
+			return nil, err
+		}
+		if rec, ok := rec.(orient.ORecord); ok {
+			s.db.updateCachedRecord(rec)
+		}
+		if status == 1 && (rec != nil || !s.iter) {
+			return rec, s.r.Err()
+		}
+	}
+}
+
+// readTail reads records prefetched by the server after the command result.
+func (s *commandStream) readTail() error {
+	if _, err := s.db.readPrefetched(s.r); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// abandon stops reading records. Remaining records are consumed in background to keep the connection usable,
+// and the session stays locked until then.
+func (s *commandStream) abandon(err error) {
+	s.err = err
+	s.done = true
+	go func() {
+		var err error
+		defer s.release()
+		defer s.db.sess.catch(&err)
+		for err == nil {
+			_, err = s.read()
+		}
+	}()
+}
+
+func (s *commandStream) close() {
+	if s.done {
+		return
+	}
+	s.done = true
+	s.release()
+}
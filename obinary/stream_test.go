@@ -0,0 +1,125 @@
+package obinary_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+func writeStreamRID(w *rw.Writer, status byte, pos int64) {
+	if status != 0 {
+		w.WriteByte(status)
+	}
+	w.WriteShort(obinary.RecordRID)
+	orient.RID{ClusterID: 9, ClusterPos: pos}.ToStream(w)
+}
+
+func TestCommandStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := rw.NewWriter(buf)
+	w.WriteByte('i')
+	writeStreamRID(w, 1, 1)
+	writeStreamRID(w, 2, 2) // prefetched record
+	writeStreamRID(w, 1, 3)
+	w.WriteByte(0)
+	writeStreamRID(w, 2, 4) // prefetched records after the result
+	w.WriteByte(0)
+
+	released := 0
+	st, err := obinary.ReadCommandStream(rw.NewReader(buf), func() { released++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.Value(); ok {
+		t.Fatal("collection was returned as a value")
+	}
+	var recs []orient.OIdentifiable
+	for {
+		rec, err := st.NextContext(context.Background())
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	equals(t, []orient.OIdentifiable{orient.RID{ClusterID: 9, ClusterPos: 1}, orient.RID{ClusterID: 9, ClusterPos: 3}}, recs)
+	equals(t, 1, released)
+	equals(t, 0, buf.Len())
+	equals(t, nil, st.Close())
+	equals(t, 1, released)
+}
+
+func TestCommandStreamClose(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := rw.NewWriter(buf)
+	w.WriteByte('l')
+	w.WriteInt(3)
+	for i := 1; i <= 3; i++ {
+		writeStreamRID(w, 0, int64(i))
+	}
+	w.WriteByte(0)
+
+	released := 0
+	st, err := obinary.ReadCommandStream(rw.NewReader(buf), func() { released++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := st.NextContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, orient.RID{ClusterID: 9, ClusterPos: 1}, rec)
+	equals(t, 0, released)
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, 1, released)
+	equals(t, 0, buf.Len()) // remaining records were drained
+	_, err = st.NextContext(context.Background())
+	equals(t, io.EOF, err)
+}
+
+func writeStreamDoc(t *testing.T, w *rw.Writer, status byte, pos int64, doc *orient.Document) {
+	var content bytes.Buffer
+	if err := orient.GetDefaultRecordSerializer().ToStream(&content, doc); err != nil {
+		t.Fatal(err)
+	}
+	if status != 0 {
+		w.WriteByte(status)
+	}
+	w.WriteShort(0)
+	w.WriteByte(byte(orient.RecordTypeDocument))
+	orient.RID{ClusterID: 9, ClusterPos: pos}.ToStream(w)
+	w.WriteInt(1)
+	w.WriteBytes(content.Bytes())
+}
+
+func TestCommandResultPrefetched(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := rw.NewWriter(buf)
+	w.WriteByte('l')
+	w.WriteInt(1)
+	writeStreamDoc(t, w, 0, 1, orient.NewDocument("Cat").SetField("owner", orient.RID{ClusterID: 9, ClusterPos: 2}))
+	writeStreamDoc(t, w, 2, 2, orient.NewDocument("Person").SetField("name", "Linus"))
+	w.WriteByte(0)
+
+	// links are resolved without records cache
+	res, err := obinary.ReadCommandResult(rw.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := res.([]orient.OIdentifiable)
+	equals(t, 1, len(recs))
+	owner, ok := recs[0].(*orient.Document).GetField("owner").Value.(*orient.Document)
+	if !ok {
+		t.Fatalf("link was not resolved: %v", recs[0])
+	}
+	equals(t, "Linus", owner.GetField("name").Value)
+	equals(t, 0, buf.Len())
+}
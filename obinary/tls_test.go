@@ -0,0 +1,96 @@
+package obinary_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary"
+)
+
+// selfSignedCert generates a certificate for 127.0.0.1 that can be used both by server and client.
+func selfSignedCert(t testing.TB) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "orientgo test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestDialTLS(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err != nil {
+					return
+				}
+				var vers [2]byte
+				binary.BigEndian.PutUint16(vers[:], uint16(obinary.CurrentProtoVersion))
+				conn.Write(vers[:])
+				var buf [1]byte
+				conn.Read(buf[:]) // wait for client to disconnect
+			}()
+		}
+	}()
+
+	cli, err := obinary.DialWithOptions(l.Addr().String(), orient.Options{TLSConfig: &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Close()
+
+	// connection without client certificate must be refused
+	cli, err = obinary.DialWithOptions(l.Addr().String(), orient.Options{TLSConfig: &tls.Config{RootCAs: pool}})
+	if err == nil {
+		cli.Close()
+		t.Fatal("expected handshake error")
+	}
+}
@@ -0,0 +1,87 @@
+package obinary_test
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+// tokenServer answers the first request with a renewed token and fails the second one,
+// reporting tokens sent by the client.
+func tokenServer(t testing.TB, tokens chan<- string) (addr string, closer io.Closer) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var vers [2]byte
+		binary.BigEndian.PutUint16(vers[:], uint16(obinary.CurrentProtoVersion))
+		conn.Write(vers[:])
+
+		r, w := rw.NewReader(conn), rw.NewWriter(conn)
+		readReq := func() int32 {
+			r.ReadByte()
+			sid := r.ReadInt()
+			tokens <- string(r.ReadBytes())
+			return sid
+		}
+		// REQUEST_DB_RELOAD
+		sid := readReq()
+		w.WriteByte(0)
+		w.WriteInt(sid)
+		w.WriteBytes([]byte("tok2"))
+		w.WriteShort(1)
+		w.WriteString("default")
+		w.WriteShort(3)
+		// load of config record
+		sid = readReq()
+		w.WriteByte(1)
+		w.WriteInt(sid)
+		w.WriteBytes(nil)
+		w.WriteByte(1)
+		w.WriteString("com.orientechnologies.orient.core.exception.OSecurityException")
+		w.WriteString("token expired")
+		w.WriteByte(0)
+		w.WriteBytes(nil)
+		io.Copy(ioutil.Discard, conn)
+	}()
+	return l.Addr().String(), l
+}
+
+func TestOpenWithToken(t *testing.T) {
+	tokens := make(chan string, 2)
+	addr, l := tokenServer(t, tokens)
+	defer l.Close()
+	cli, err := obinary.DialWithOptions(addr, orient.Options{UseToken: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	db, err := cli.OpenDatabaseWithToken("db", orient.DocumentDB, orient.SessionToken{SessionID: 5, Token: []byte("tok1")})
+	if err == nil || !strings.Contains(err.Error(), "token expired") {
+		t.Fatalf("expected server error, got: %v", err)
+	}
+	if tok := <-tokens; tok != "tok1" {
+		t.Fatalf("unexpected token in first request: %q", tok)
+	}
+	if tok := <-tokens; tok != "tok2" {
+		t.Fatalf("renewed token was not used: %q", tok)
+	}
+	if db == nil {
+		t.Fatal("expected database")
+	} else if tok := db.Token(); tok.SessionID != 5 || string(tok.Token) != "tok2" {
+		t.Fatalf("unexpected session token: %+v", tok)
+	}
+}
@@ -0,0 +1,122 @@
+package obinary
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/nu7hatch/gouuid"
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+// Commit sends a list of transaction operations to the server in one REQUEST_TX_COMMIT
+// and updates RIDs and versions of records.
+func (db *Database) Commit(txID int, ops []orient.TxOp) error {
+	return db.CommitContext(context.Background(), txID, ops)
+}
+
+// CommitContext is like Commit, but allows to cancel the request with a context.
+func (db *Database) CommitContext(ctx context.Context, txID int, ops []orient.TxOp) error {
+	contents := make([][]byte, len(ops))
+	for i, op := range ops {
+		if op.Type == orient.TxOpDelete {
+			continue
+		} else if op.Record == nil {
+			return fmt.Errorf("no record for %v operation on %v", op.Type, op.RID)
+		}
+		if doc, ok := op.Record.(*orient.Document); ok {
+			doc.SetSerializer(db.serializer())
+		}
+		data, err := op.Record.Content()
+		if err != nil {
+			return err
+		}
+		contents[i] = data
+	}
+	var (
+		created = make(map[orient.RID]orient.RID)
+		updated = make(map[orient.RID]int)
+		ptrs    map[uuid.UUID]orient.BonsaiPointer
+	)
+	if err := db.sess.sendCmdContext(ctx, requestTxCommit, func(w *rw.Writer) error {
+		w.WriteInt(int32(txID))
+		w.WriteBool(true) // using tx log
+		for i, op := range ops {
+			w.WriteByte(1) // next entry
+			w.WriteByte(byte(op.Type))
+			if err := op.RID.ToStream(w); err != nil {
+				return err
+			}
+			if op.Record != nil {
+				w.WriteByte(byte(op.Record.RecordType()))
+			} else {
+				w.WriteByte(byte(orient.RecordTypeDocument))
+			}
+			switch op.Type {
+			case orient.TxOpCreate:
+				w.WriteBytes(contents[i])
+			case orient.TxOpUpdate:
+				w.WriteInt(int32(op.Version))
+				w.WriteBool(true) // update-content flag
+				w.WriteBytes(contents[i])
+			case orient.TxOpDelete:
+				w.WriteInt(int32(op.Version))
+			}
+		}
+		w.WriteByte(0) // end of entries
+		// index changes are tracked on the server side, send an empty document
+		buf := bytes.NewBuffer(nil)
+		if err := db.serializer().ToStream(buf, orient.NewEmptyDocument()); err != nil {
+			return err
+		}
+		w.WriteBytes(buf.Bytes())
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		n := int(r.ReadInt())
+		for i := 0; i < n; i++ {
+			var tmp, rid orient.RID
+			if err := tmp.FromStream(r); err != nil {
+				return err
+			}
+			if err := rid.FromStream(r); err != nil {
+				return err
+			}
+			created[tmp] = rid
+		}
+		n = int(r.ReadInt())
+		for i := 0; i < n; i++ {
+			var rid orient.RID
+			if err := rid.FromStream(r); err != nil {
+				return err
+			}
+			updated[rid] = int(r.ReadInt())
+		}
+		ptrs = readCollectionChanges(r)
+		return r.Err()
+	}); err != nil {
+		return err
+	}
+	for i, op := range ops {
+		if op.Record == nil {
+			db.cache.Remove(op.RID)
+			continue
+		}
+		rid, vers := op.RID, op.Version
+		if op.Type == orient.TxOpCreate {
+			if nrid, ok := created[rid]; ok {
+				rid = nrid
+			}
+			vers = 1
+		}
+		if v, ok := updated[rid]; ok {
+			vers = v
+		}
+		setRidBagPointers(op.Record, ptrs)
+		if err := op.Record.Fill(rid, vers, contents[i]); err != nil {
+			return err
+		}
+		db.updateCachedRecord(op.Record)
+	}
+	return nil
+}
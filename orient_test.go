@@ -1,6 +1,7 @@
 package orient_test
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
@@ -59,6 +60,36 @@ func TestDBAuthWrong(t *testing.T) {
 	}
 }
 
+func TestServerAdmin(t *testing.T) {
+	notShort(t)
+	cli, closer := SpinOrient(t)
+	defer closer()
+	admin, err := cli.Auth(srvUser, srvPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := admin.ServerConfig()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(conf) == 0 {
+		t.Fatal("empty server config")
+	}
+	for key, val := range conf {
+		if v, err := admin.ServerConfigValue(key); err != nil {
+			t.Fatal(err)
+		} else if v != val {
+			t.Fatalf("config value of %q: %q vs %q", key, v, val)
+		}
+		break
+	}
+	if err = admin.FreezeDatabase(dbName, orient.Persistent); err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.ReleaseDatabase(dbName, orient.Persistent); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func SpinOrientServer(t *testing.T) (string, func()) {
 	const port = 2424
 	if orientVersion == "local" {
@@ -189,6 +220,12 @@ func TestSelect(t *testing.T) {
 	})
 }
 
+func TestSelectQuery(t *testing.T) {
+	testOUserCommand(t, func(cli *orient.Database) orient.Results {
+		return cli.Query("SELECT FROM OUser LIMIT 3")
+	})
+}
+
 func TestSelectCommand(t *testing.T) {
 	testOUserCommand(t, func(cli *orient.Database) orient.Results {
 		if orientVersion < "2.1" {
@@ -679,3 +716,476 @@ func TestConcurrentModification(t *testing.T) {
 		}
 	}
 }
+
+func TestTransaction(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	tx := db.Begin()
+	cat := orient.NewDocument("Cat")
+	cat.SetField("name", "Tom")
+	if err := tx.CreateRecord(cat); err != nil {
+		t.Fatal(err)
+	} else if !cat.RID.IsTemporary() {
+		t.Fatalf("expected temporary rid, got %v", cat.RID)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	} else if !cat.RID.IsPersistent() {
+		t.Fatalf("rid was not updated: %v", cat.RID)
+	}
+
+	rec, err := db.GetRecordByRID(cat.RID, orient.DefaultFetchPlan, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := rec.(*orient.Document)
+	if name := doc.GetField("name"); name == nil || name.Value != "Tom" {
+		t.Fatalf("unexpected record: %v", doc)
+	}
+
+	tx = db.Begin()
+	if err = tx.DeleteRecordByRID(cat.RID, cat.Version()); err != nil {
+		t.Fatal(err)
+	} else if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.GetRecordByRID(cat.RID, orient.DefaultFetchPlan, true); err != nil {
+		t.Fatal("record was deleted by rolled back transaction:", err)
+	}
+}
+
+func TestFetchPlanCache(t *testing.T) {
+	notShort(t)
+	addr, rm := SpinOrientServer(t)
+	defer rm()
+	cli, err := orient.DialWithOptions(addr, orient.Options{RecordCacheSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	db, err := cli.Open(dbName, orient.DocumentDB, dbUser, dbPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catch(t)
+	SeedDB(t, db)
+
+	owner := orient.NewDocument("Cat")
+	owner.SetField("name", "Owner")
+	if err := db.CreateRecord(owner); err != nil {
+		t.Fatal(err)
+	}
+	cat := orient.NewDocument("Cat")
+	cat.SetField("name", "Pet")
+	cat.SetField("owner", owner.RID)
+	if err := db.CreateRecord(cat); err != nil {
+		t.Fatal(err)
+	}
+	db.Cache().Clear()
+
+	rec, err := db.GetRecordByRID(cat.RID, orient.FollowAll, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, ok := rec.(*orient.Document).GetField("owner").Value.(*orient.Document)
+	if !ok {
+		t.Fatalf("link was not resolved: %v", rec)
+	} else if name := link.GetField("name"); name == nil || name.Value != "Owner" {
+		t.Fatalf("unexpected linked record: %v", link)
+	}
+	if db.Cache().Get(owner.RID) == nil {
+		t.Fatal("prefetched record was not cached")
+	}
+}
+
+func TestRemoteRidBag(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, true)
+	defer closer()
+	defer catch(t)
+
+	const n = 50 // more than default embedded RidBag threshold
+	var hub orient.Document
+	if err := db.Command(orient.NewSQLCommand(`CREATE VERTEX V SET name = 'hub'`)).All(&hub); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		err := db.Command(orient.NewSQLCommand(fmt.Sprintf(`CREATE EDGE E FROM %v TO (CREATE VERTEX V SET i = %d)`, hub.RID, i))).Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec, err := db.GetRecordByRID(hub.RID, orient.DefaultFetchPlan, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, ok := rec.(*orient.Document).GetField("out_E").Value.(*orient.RidBag)
+	if !ok {
+		t.Fatalf("expected RidBag, got %v", rec.(*orient.Document).GetField("out_E"))
+	} else if !bag.IsRemote() {
+		t.Skip("RidBag is embedded")
+	}
+	if size, err := bag.Size(db); err != nil {
+		t.Fatal(err)
+	} else if size != n {
+		t.Fatalf("expected %d links, got %d", n, size)
+	}
+	cnt := 0
+	for it := bag.Iterator(db); it.Next(); cnt++ {
+	}
+	if cnt != n {
+		t.Fatalf("expected %d links, got %d", n, cnt)
+	}
+}
+
+func TestLoadRecords(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	var rids []orient.RID
+	for i := 0; i < 5; i++ {
+		doc := orient.NewDocument("Cat")
+		doc.SetField("name", fmt.Sprintf("cat%d", i))
+		if err := db.CreateRecord(doc); err != nil {
+			t.Fatal(err)
+		}
+		rids = append([]orient.RID{doc.RID}, rids...)
+	}
+	missing := rids[0].NextRID()
+	rids = append(rids, missing)
+	db.Cache().Clear()
+
+	recs, err := db.LoadRecords(rids, orient.DefaultFetchPlan)
+	if e, ok := err.(orient.ErrMissingRecords); !ok || len(e.RIDs) != 1 || e.RIDs[0] != missing {
+		t.Fatalf("expected missing record error, got %v", err)
+	}
+	for i, rec := range recs[:len(recs)-1] {
+		name := rec.(*orient.Document).GetField("name")
+		if exp := fmt.Sprintf("cat%d", 4-i); name == nil || name.Value != exp {
+			t.Fatalf("expected %s, got %v", exp, name)
+		}
+	}
+}
+
+func TestBrowseCluster(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	if _, err := db.AddCluster("browse"); err != nil {
+		t.Fatal(err)
+	}
+	var rids []orient.RID
+	for i := 0; i < 10; i++ {
+		var doc orient.Document
+		if err := db.Command(orient.NewSQLCommand(`INSERT INTO cluster:browse SET i = ?`, i)).All(&doc); err != nil {
+			t.Fatal(err)
+		}
+		rids = append(rids, doc.RID)
+	}
+	if err := db.DeleteRecordByRID(rids[3], -1); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	it := db.BrowseCluster("browse")
+	for it.Next() {
+		if rid := it.Record().GetIdentity(); rid == rids[3] {
+			t.Fatal("deleted record returned")
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	} else if n != 9 {
+		t.Fatalf("expected 9 records, got %d", n)
+	}
+	for n = 0; it.Prev(); n++ {
+	}
+	if n != 9 {
+		t.Fatalf("expected 9 records in reverse, got %d", n)
+	}
+}
+
+func TestRecordMetadata(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	var docs [2]orient.Document
+	for i := range docs {
+		if err := db.Command(orient.NewSQLCommand(`INSERT INTO V SET i = ?`, i)).All(&docs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vers, err := db.RecordMetadata(docs[0].GetIdentity())
+	if err != nil {
+		t.Fatal(err)
+	} else if vers != docs[0].Version() {
+		t.Fatalf("unexpected version: %d vs %d", vers, docs[0].Version())
+	}
+	if err = db.Command(orient.NewSQLCommand(`UPDATE ` + docs[0].GetIdentity().String() + ` SET i = 5`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if vers2, err := db.RecordMetadata(docs[0].GetIdentity()); err != nil {
+		t.Fatal(err)
+	} else if vers2 <= vers {
+		t.Fatalf("version was not changed: %d", vers2)
+	}
+
+	if err = db.HideRecord(docs[0].GetIdentity()); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.CleanOutRecord(docs[1].GetIdentity(), docs[1].Version()); err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if rec, err := db.GetRecordByRID(doc.GetIdentity(), "", true); err == nil && rec != nil {
+			t.Fatalf("record %v is still available", doc.GetIdentity())
+		}
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	err := db.Command(orient.NewSQLCommand(`SELECT FORM V`)).Err()
+	if !errors.Is(err, orient.ErrCommandSyntax{}) {
+		t.Fatalf("expected syntax error, got: %T: %v", err, err)
+	}
+	var serr orient.ErrCommandSyntax
+	if errors.As(err, &serr); serr.Exception == nil || len(serr.Exception.(orient.StackException).ExcStack()) == 0 {
+		t.Fatal("no server stack in error")
+	}
+}
+
+func TestSchemaRefresh(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	changed := make(chan *orient.ODatabase, 2)
+	db.OnSchemaChange(func(schema *orient.ODatabase) {
+		changed <- schema
+	})
+	if err := db.Command(orient.NewSQLCommand(`CREATE CLASS Fresh`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case schema := <-changed:
+		if _, ok := schema.Classes["Fresh"]; !ok {
+			t.Fatal("new class is not in the schema passed to hook")
+		}
+	default:
+		t.Fatal("schema hook was not called")
+	}
+	if _, ok := db.GetCurDB().Classes["Fresh"]; !ok {
+		t.Fatal("new class is not in the schema")
+	}
+	if err := db.Command(orient.NewSQLCommand(`DROP CLASS Fresh`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.GetCurDB().Classes["Fresh"]; ok {
+		t.Fatal("dropped class is still in the schema")
+	}
+}
+
+func TestStorageInfo(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	info, err := db.StorageInfo()
+	if err != nil {
+		t.Fatal(err)
+	} else if info.DateFormat == "" || info.TimeZone == "" {
+		t.Fatalf("incomplete storage info: %+v", info)
+	}
+	found := false
+	for _, c := range info.Clusters {
+		if c.Name == "cat" {
+			found = true
+			if c.Class != "Cat" {
+				t.Fatalf("wrong class of cluster: %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("class cluster not found: %+v", info.Clusters)
+	}
+}
+
+func TestCommandStreaming(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	results := db.Command(orient.NewSQLQuery("SELECT FROM Cat ORDER BY name"))
+	var (
+		doc   *orient.Document
+		names []string
+	)
+	for results.Next(&doc) {
+		names = append(names, doc.GetField("name").Value.(string))
+		if len(names) == 1 { // session is held by results, so other requests use another one
+			var cnt []*orient.Document
+			if err := db.Command(orient.NewSQLQuery("SELECT count(*) FROM Cat")).All(&cnt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := results.Close(); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 2 || names[0] != "Keiko" {
+		t.Fatalf("unexpected records: %v", names)
+	}
+
+	// not all records are read, the rest must be drained on close
+	results = db.Command(orient.NewSQLQuery("SELECT FROM Cat"))
+	if !results.Next(&doc) {
+		t.Fatal(results.Err())
+	} else if err := results.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var docs []*orient.Document
+	if err := db.Command(orient.NewSQLQuery("SELECT FROM Cat")).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 2 {
+		t.Fatalf("wrong number of records: %d", len(docs))
+	} else if st := db.Stats(); st.InUse != 0 {
+		t.Fatalf("sessions are held by closed results: %+v", st)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	for _, sql := range []string{
+		"ALTER CLASS Cat ADDCLUSTER cat_extra",
+		"INSERT INTO Cat CLUSTER cat_extra (name, age) VALUES ('Tom', 3)",
+	} {
+		if err := db.Command(orient.NewSQLCommand(sql)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := db.Paginate(orient.NewSQLQuery("SELECT FROM Cat WHERE age > ?", 1), 2)
+	var (
+		sizes []int
+		rids  = make(map[orient.RID]bool)
+	)
+	for pages.Next() {
+		sizes = append(sizes, len(pages.Page()))
+		for _, rec := range pages.Page() {
+			rids[rec.GetIdentity()] = true
+		}
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal(err)
+	} else if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 || len(rids) != 3 {
+		t.Fatalf("unexpected pages: %v, %v", sizes, rids)
+	}
+}
+
+func TestStructMetadata(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	type Cat struct {
+		RID     orient.RID `orient:"@rid"`
+		Version int        `orient:"@version"`
+		Class   string     `orient:"@class"`
+		Name    string     `orient:"name"`
+		Age     int        `orient:"age"`
+	}
+	cat := Cat{Class: "Cat", Name: "Tom", Age: 3}
+	doc := orient.NewEmptyDocument()
+	if err := doc.From(&cat); err != nil {
+		t.Fatal(err)
+	} else if err = db.CreateRecord(doc); err != nil {
+		t.Fatal(err)
+	} else if !cat.RID.IsPersistent() || cat.Version <= 0 {
+		t.Fatalf("metadata was not set: %+v", cat)
+	}
+
+	stale := cat
+	cat.Age = 4
+	doc = orient.NewEmptyDocument()
+	doc.From(&cat)
+	if err := db.UpdateRecord(doc); err != nil {
+		t.Fatal(err)
+	} else if cat.Version <= stale.Version {
+		t.Fatalf("version was not updated: %+v", cat)
+	}
+	doc = orient.NewEmptyDocument()
+	doc.From(&stale)
+	if err := db.UpdateRecord(doc); err == nil {
+		t.Fatal("update with stale version succeeded")
+	}
+
+	var cats []Cat
+	if err := db.Command(orient.NewSQLQuery("SELECT FROM Cat WHERE name = ?", "Tom")).All(&cats); err != nil {
+		t.Fatal(err)
+	} else if len(cats) != 1 || cats[0] != cat {
+		t.Fatalf("unexpected results: %+v vs %+v", cats, cat)
+	}
+}
+
+func TestLiveQuery(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	sub, err := db.LiveQuery("LIVE SELECT FROM Cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	err = db.Command(orient.NewSQLCommand("INSERT INTO Cat (name, age) VALUES ('Tom', 3)")).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-sub.Events():
+		if ev.Op != orient.LiveCreated {
+			t.Fatalf("unexpected operation: %v", ev.Op)
+		}
+		doc := ev.Record.(*orient.Document)
+		if name := doc.GetField("name").Value; name != "Tom" {
+			t.Fatalf("unexpected record: %v", doc)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("no live query events received")
+	}
+	if err = sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("events channel should be closed")
+	}
+}
@@ -0,0 +1,245 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Paginate returns an iterator over pages of query results. Each page is loaded by a separate query that continues
+// after RID of the last record of the previous page, so no server-side state is held between pages:
+//
+//	SELECT FROM Cat WHERE (age > ?) AND @rid > #12:345 ORDER BY @rid LIMIT 100
+//
+// Records are ordered by RID, thus classes with multiple clusters are paginated correctly. Records inserted or
+// deleted during iteration may or may not be returned.
+//
+// Query must be a SELECT without ORDER BY, GROUP BY, SKIP, LIMIT and UNWIND clauses; total number of records can be
+// limited with SQLQuery.Limit. Queries with projections must return RIDs of records (SELECT @rid, name FROM ...).
+//
+//	pages := db.Paginate(NewSQLQuery("SELECT FROM Cat WHERE age > ?", 10), 100)
+//	for pages.Next() {
+//		for _, rec := range pages.Page() {
+//			// ...
+//		}
+//	}
+//	if err := pages.Err(); err != nil {
+//		// ...
+//	}
+func (db *Database) Paginate(q SQLQuery, pageSize int) *PageIterator {
+	left := q.limit
+	if left <= 0 {
+		left = -1
+	}
+	return &PageIterator{db: db, q: q, size: pageSize, last: NewEmptyRID(), left: left}
+}
+
+// PageIterator iterates over pages of query results. See Database.Paginate.
+type PageIterator struct {
+	db   *Database
+	q    SQLQuery
+	size int
+
+	parsed           bool
+	head, cond, tail string // parts of query text before, inside and after WHERE clause
+
+	last RID // RID of the last returned record; invalid before the first page
+	left int // number of records left according to query limit; negative if not limited
+	page []OIdentifiable
+	eof  bool
+	err  error
+}
+
+// Next loads the next page of results. It returns false when there are no more records or on error (see Err).
+func (it *PageIterator) Next() bool {
+	return it.NextContext(context.Background())
+}
+
+// NextContext is like Next, but allows to cancel the request with a context.
+func (it *PageIterator) NextContext(ctx context.Context) bool {
+	it.page = nil
+	if it.err != nil || it.eof {
+		return false
+	}
+	if !it.parsed {
+		if it.size <= 0 {
+			it.err = fmt.Errorf("invalid page size: %d", it.size)
+			return false
+		}
+		it.head, it.cond, it.tail, it.err = splitPageQuery(it.q.text)
+		if it.err != nil {
+			return false
+		}
+		it.parsed = true
+	}
+	n := it.size
+	if it.left >= 0 && it.left < n {
+		n = it.left
+	}
+	if n == 0 {
+		it.eof = true
+		return false
+	}
+	var page []OIdentifiable
+	if err := it.db.CommandContext(ctx, it.pageQuery(n)).All(&page); err != nil {
+		it.err = err
+		return false
+	}
+	it.eof = len(page) < n // short page is the last one, no need to ask for the next
+	if len(page) == 0 {
+		return false
+	}
+	last, err := pageRecordRID(page[len(page)-1])
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.last = last
+	if it.left > 0 {
+		it.left -= len(page)
+	}
+	it.page = page
+	return true
+}
+
+// Page returns records of the current page.
+func (it *PageIterator) Page() []OIdentifiable {
+	return it.page
+}
+
+// Err returns an error that occurred during iteration.
+func (it *PageIterator) Err() error {
+	return it.err
+}
+
+// pageQuery builds a query for the next page of n records.
+func (it *PageIterator) pageQuery(n int) SQLQuery {
+	cond := it.cond
+	if it.last.IsValid() {
+		after := "@rid > " + it.last.String()
+		if cond != "" {
+			cond = "(" + cond + ") AND " + after
+		} else {
+			cond = after
+		}
+	}
+	text := it.head
+	if cond != "" {
+		text += " WHERE " + cond
+	}
+	text += fmt.Sprintf(" ORDER BY @rid LIMIT %d", n)
+	if it.tail != "" {
+		text += " " + it.tail
+	}
+	q := it.q
+	q.text, q.limit = text, -1
+	return q
+}
+
+// pageRecordRID returns RID of a record returned by paginated query. For projections it's taken from rid field.
+func pageRecordRID(rec OIdentifiable) (RID, error) {
+	if rec == nil {
+		return NewEmptyRID(), fmt.Errorf("cannot paginate: query returned null record")
+	}
+	rid := rec.GetIdentity()
+	if rid.IsPersistent() {
+		return rid, nil
+	}
+	if doc, ok := rec.(*Document); ok {
+		for _, name := range []string{"rid", "@rid"} {
+			if fld := doc.GetField(name); fld != nil {
+				if id, ok := fld.Value.(OIdentifiable); ok && id.GetIdentity().IsPersistent() {
+					return id.GetIdentity(), nil
+				}
+			}
+		}
+	}
+	return rid, fmt.Errorf("cannot paginate: query result has no @rid (%v)", rid)
+}
+
+// splitPageQuery splits text of SELECT query to parts before, inside and after WHERE clause.
+// Queries with clauses that conflict with pagination are rejected.
+func splitPageQuery(sql string) (head, cond, tail string, err error) {
+	sql = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sql), ";"))
+	words := sqlTopLevelWords(sql)
+	if len(words) == 0 || words[0].word != "SELECT" {
+		return "", "", "", fmt.Errorf("cannot paginate: not a SELECT query: %q", sql)
+	}
+	from := -1
+	for i, w := range words {
+		if w.word == "FROM" {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return "", "", "", fmt.Errorf("cannot paginate: no query target: %q", sql)
+	}
+	from++ // keywords in projections are ignored
+	if rest := strings.TrimSpace(sql[words[from-1].pos+len("FROM"):]); rest != "" && !strings.ContainsAny(rest[:1], "([") {
+		from++ // query target is a class or cluster name, which can be a keyword too
+	}
+	if from > len(words) {
+		from = len(words)
+	}
+	where, end := -1, len(sql)
+	for _, w := range words[from:] {
+		switch w.word {
+		case "WHERE":
+			if where < 0 {
+				where = w.pos
+			}
+		case "ORDER", "GROUP", "SKIP", "OFFSET", "LIMIT", "UNWIND":
+			return "", "", "", fmt.Errorf("cannot paginate query with %s clause: %q", w.word, sql)
+		case "FETCHPLAN", "TIMEOUT", "LOCK", "PARALLEL", "NOCACHE":
+			if w.pos < end {
+				end = w.pos
+			}
+		}
+	}
+	tail = strings.TrimSpace(sql[end:])
+	if where >= 0 && where < end {
+		return strings.TrimSpace(sql[:where]), strings.TrimSpace(sql[where+len("WHERE") : end]), tail, nil
+	}
+	return strings.TrimSpace(sql[:end]), "", tail, nil
+}
+
+type sqlWord struct {
+	pos  int
+	word string // upper-cased
+}
+
+// sqlTopLevelWords returns words of SQL text that are not inside of quotes, parentheses or brackets.
+func sqlTopLevelWords(sql string) (words []sqlWord) {
+	isWord := func(c byte) bool {
+		return c == '_' || c == '@' || c == '$' || c == '#' || c == ':' || c == '.' ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+	}
+	depth := 0
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; c {
+		case '\'', '"', '`':
+			for i++; i < len(sql) && sql[i] != c; i++ {
+				if sql[i] == '\\' {
+					i++
+				}
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		default:
+			if !isWord(c) {
+				continue
+			}
+			start := i
+			for i+1 < len(sql) && isWord(sql[i+1]) {
+				i++
+			}
+			if depth == 0 {
+				words = append(words, sqlWord{pos: start, word: strings.ToUpper(sql[start : i+1])})
+			}
+		}
+	}
+	return words
+}
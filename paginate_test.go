@@ -0,0 +1,105 @@
+package orient
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// pageSession serves records of a class with multiple clusters, filtering them by RID like a server would.
+type pageSession struct {
+	testSession
+	rids    []RID // sorted
+	queries []string
+}
+
+var (
+	rePageAfter = regexp.MustCompile(`@rid > (#\d+:\d+)`)
+	rePageLimit = regexp.MustCompile(`LIMIT (\d+)`)
+)
+
+func (s *pageSession) CommandContext(ctx context.Context, cmd CustomSerializable) (interface{}, error) {
+	text := cmd.(SQLQuery).text
+	s.queries = append(s.queries, text)
+	after := NewEmptyRID()
+	if m := rePageAfter.FindStringSubmatch(text); m != nil {
+		after = MustParseRID(m[1])
+	}
+	limit, _ := strconv.Atoi(rePageLimit.FindStringSubmatch(text)[1])
+	out := []OIdentifiable{}
+	for _, rid := range s.rids {
+		if len(out) < limit && (rid.ClusterID > after.ClusterID || (rid.ClusterID == after.ClusterID && rid.ClusterPos > after.ClusterPos)) {
+			out = append(out, rid)
+		}
+	}
+	return out, nil
+}
+
+func TestPaginate(t *testing.T) {
+	sess := &pageSession{rids: []RID{NewRID(9, 0), NewRID(9, 1), NewRID(9, 5), NewRID(10, 0), NewRID(10, 2)}}
+	db := newTestDB(t, func() DBSession { return sess })
+
+	collect := func(it *PageIterator) (pages [][]OIdentifiable) {
+		for it.Next() {
+			pages = append(pages, it.Page())
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return pages
+	}
+
+	pages := collect(db.Paginate(NewSQLQuery("SELECT FROM V WHERE a = ? OR b = ?", 1, 2), 2))
+	if len(pages) != 3 || len(pages[2]) != 1 || pages[1][1] != NewRID(10, 0) {
+		t.Fatalf("unexpected pages: %v", pages)
+	} else if len(sess.queries) != 3 {
+		t.Fatalf("short page must be the last one: %q", sess.queries)
+	} else if exp := "SELECT FROM V WHERE (a = ? OR b = ?) AND @rid > #10:0 ORDER BY @rid LIMIT 2"; sess.queries[2] != exp {
+		t.Fatalf("unexpected query:\n%s\nvs\n%s", sess.queries[2], exp)
+	}
+
+	sess.queries = nil
+	pages = collect(db.Paginate(NewSQLQuery("SELECT FROM V").Limit(3), 2))
+	if len(pages) != 2 || len(pages[1]) != 1 || pages[1][0] != NewRID(9, 5) {
+		t.Fatalf("unexpected pages: %v", pages)
+	} else if exp := "SELECT FROM V WHERE @rid > #9:1 ORDER BY @rid LIMIT 1"; sess.queries[1] != exp {
+		t.Fatalf("unexpected query:\n%s\nvs\n%s", sess.queries[1], exp)
+	}
+
+	it := db.Paginate(NewSQLQuery("SELECT FROM V ORDER BY name"), 2)
+	if it.Next() || it.Err() == nil {
+		t.Fatal("query with ORDER BY must be rejected")
+	}
+}
+
+func TestSplitPageQuery(t *testing.T) {
+	cases := []struct {
+		sql              string
+		head, cond, tail string
+	}{
+		{sql: "SELECT FROM V", head: "SELECT FROM V"},
+		{sql: "select name, @rid from V where name = 'a where b' fetchplan *:0", head: "select name, @rid from V", cond: "name = 'a where b'", tail: "fetchplan *:0"},
+		{sql: "SELECT FROM Order WHERE out('limit').size() > 0;", head: "SELECT FROM Order", cond: "out('limit').size() > 0"},
+		{sql: "SELECT FROM (SELECT FROM V ORDER BY a) WHERE b = 1", head: "SELECT FROM (SELECT FROM V ORDER BY a)", cond: "b = 1"},
+		{sql: "SELECT FROM cluster:limit LET $a = (SELECT 1) TIMEOUT 100", head: "SELECT FROM cluster:limit LET $a = (SELECT 1)", tail: "TIMEOUT 100"},
+	}
+	for _, c := range cases {
+		head, cond, tail, err := splitPageQuery(c.sql)
+		if err != nil {
+			t.Fatalf("%q: %v", c.sql, err)
+		} else if head != c.head || cond != c.cond || tail != c.tail {
+			t.Fatalf("%q: unexpected split: %q, %q, %q", c.sql, head, cond, tail)
+		}
+	}
+	for _, sql := range []string{
+		"UPDATE V SET a = 1",
+		"SELECT FROM V LIMIT 10",
+		"SELECT FROM V WHERE a = 1 SKIP 10",
+		"SELECT count(*) FROM V GROUP BY a",
+	} {
+		if _, _, _, err := splitPageQuery(sql); err == nil {
+			t.Fatalf("%q: query must be rejected", sql)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package orient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PoolStats contains connection pool statistics.
+type PoolStats struct {
+	MaxOpen int // maximal number of open sessions; negative means no limit
+
+	Open  int // number of established sessions, both in use and idle
+	InUse int // number of sessions currently in use
+	Idle  int // number of idle sessions
+
+	WaitCount    int64         // total number of sessions waited for
+	WaitDuration time.Duration // total time blocked waiting for a free session
+
+	MaxIdleClosed     int64 // total number of sessions closed due to Options.MaxIdleTime
+	MaxLifetimeClosed int64 // total number of sessions closed due to Options.MaxLifetime
+	BrokenClosed      int64 // total number of sessions closed due to connection errors or failed ping
+}
+
+func (s *PoolStats) add(o PoolStats) {
+	if s.MaxOpen >= 0 && o.MaxOpen >= 0 {
+		s.MaxOpen += o.MaxOpen
+	} else {
+		s.MaxOpen = -1
+	}
+	s.Open += o.Open
+	s.InUse += o.InUse
+	s.Idle += o.Idle
+	s.WaitCount += o.WaitCount
+	s.WaitDuration += o.WaitDuration
+	s.MaxIdleClosed += o.MaxIdleClosed
+	s.MaxLifetimeClosed += o.MaxLifetimeClosed
+	s.BrokenClosed += o.BrokenClosed
+}
+
+// maxIdleUnlimited limits the number of idle sessions for pools without connection limit.
+const maxIdleUnlimited = 10
+
+// aliveChecker is implemented by sessions that can check connection state without sending requests to the server.
+type aliveChecker interface {
+	Alive() bool
+}
+
+// pooledConn is a session with pool bookkeeping info.
+type pooledConn struct {
+	DBSession
+	created   time.Time
+	returned  time.Time
+	schemaGen int64 // generation of the database schema loaded by session; -1 for new sessions
+}
+
+// alive checks if session's connection is still usable, without sending requests to the server.
+func (c *pooledConn) alive() bool {
+	if ac, ok := c.DBSession.(aliveChecker); ok {
+		return ac.Alive()
+	}
+	return true
+}
+
+func newConnPool(opts Options, dial func(ctx context.Context) (DBSession, error)) *connPool {
+	size := opts.MaxConnections
+	if size == 0 {
+		size = MaxConnections
+	}
+	p := &connPool{
+		dial:        dial,
+		maxOpen:     size,
+		maxIdle:     size,
+		maxIdleTime: opts.MaxIdleTime,
+		maxLifetime: opts.MaxLifetime,
+		ping:        opts.PingOnBorrow,
+	}
+	if size > 0 {
+		p.toks = make(chan struct{}, size)
+		for i := 0; i < size; i++ {
+			p.toks <- struct{}{}
+		}
+	} else {
+		p.maxIdle = maxIdleUnlimited
+	}
+	return p
+}
+
+// connPool is a pool of database sessions. Each session in use holds a token, thus the number
+// of open sessions is limited by the number of tokens. Idle sessions are validated before reuse.
+type connPool struct {
+	dial func(ctx context.Context) (DBSession, error)
+	toks chan struct{} // nil if pool has no limit
+
+	maxOpen     int
+	maxIdle     int
+	maxIdleTime time.Duration
+	maxLifetime time.Duration
+	ping        bool
+
+	mu           sync.Mutex
+	idle         []*pooledConn // most recently returned sessions are at the end
+	open         int
+	waitCount    int64
+	waitDuration time.Duration
+	idleClosed   int64
+	lifeClosed   int64
+	brokenClosed int64
+	cleaner      chan struct{} // closed to stop background cleaner; nil if it is not running
+	closed       bool
+}
+
+func (p *connPool) getConn() (*pooledConn, error) {
+	return p.getConnContext(context.Background())
+}
+
+// getConnContext returns a pooled session or dials a new one. It will give up waiting
+// for a free session as soon as ctx is done. ErrClosedDatabase is returned after the pool is closed.
+//
+// Idle sessions are checked before reuse: expired, broken and (if enabled) not responding
+// to ping sessions are closed and replaced.
+func (p *connPool) getConnContext(ctx context.Context) (*pooledConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if p.isClosed() {
+		return nil, ErrClosedDatabase
+	}
+	if p.toks != nil {
+		select {
+		case <-p.toks:
+		default:
+			start := time.Now()
+			var err error
+			select {
+			case <-p.toks:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			p.mu.Lock()
+			p.waitCount++
+			p.waitDuration += time.Since(start)
+			p.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if p.isClosed() { // pool was closed while waiting for a free session
+		p.releaseToken()
+		return nil, ErrClosedDatabase
+	}
+	for {
+		conn := p.popIdle()
+		if conn == nil {
+			break
+		}
+		if p.ping {
+			if err := conn.PingContext(ctx); err != nil {
+				if ctx.Err() != nil {
+					p.putConn(conn)
+					return nil, ctx.Err()
+				}
+				p.closeConn(conn, &p.brokenClosed)
+				continue
+			}
+		}
+		return conn, nil
+	}
+	if p.dial == nil {
+		p.releaseToken()
+		return nil, nil
+	}
+	sess, err := p.dial(ctx)
+	if err != nil {
+		p.releaseToken()
+		return nil, err
+	}
+	now := time.Now()
+	p.mu.Lock()
+	closed := p.closed
+	if !closed {
+		p.open++
+	}
+	p.mu.Unlock()
+	if closed {
+		sess.Close()
+		p.releaseToken()
+		return nil, ErrClosedDatabase
+	}
+	return &pooledConn{DBSession: sess, created: now, returned: now, schemaGen: -1}, nil
+}
+
+func (p *connPool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// expired checks if session should be closed due to idle time or lifetime limits.
+// Counter of closed sessions of the corresponding kind is returned, or nil if session is not expired.
+func (p *connPool) expired(conn *pooledConn, now time.Time) *int64 {
+	if p.maxLifetime > 0 && now.Sub(conn.created) > p.maxLifetime {
+		return &p.lifeClosed
+	} else if p.maxIdleTime > 0 && now.Sub(conn.returned) > p.maxIdleTime {
+		return &p.idleClosed
+	} else if !conn.alive() {
+		return &p.brokenClosed
+	}
+	return nil
+}
+
+// popIdle returns the most recently used idle session. Expired and broken sessions are closed.
+func (p *connPool) popIdle() *pooledConn {
+	now := time.Now()
+	var closed []*pooledConn
+	defer func() {
+		for _, conn := range closed {
+			conn.Close()
+		}
+	}()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if cnt := p.expired(conn, now); cnt != nil {
+			*cnt++
+			p.open--
+			closed = append(closed, conn)
+			continue
+		}
+		return conn
+	}
+	return nil
+}
+
+// pushIdle puts session to the idle list. It returns false if the list is full or the pool is closed.
+func (p *connPool) pushIdle(conn *pooledConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.idle) >= p.maxIdle {
+		return false
+	}
+	p.idle = append(p.idle, conn)
+	if p.cleaner == nil && (p.maxIdleTime > 0 || p.maxLifetime > 0) {
+		p.cleaner = make(chan struct{})
+		go p.runCleaner(p.cleaner)
+	}
+	return true
+}
+
+// releaseToken returns a connection token taken by getConnContext back to the pool.
+func (p *connPool) releaseToken() {
+	if p.toks != nil {
+		select {
+		case p.toks <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// closeConn closes an open session and increments a given counter.
+func (p *connPool) closeConn(conn *pooledConn, cnt *int64) {
+	p.mu.Lock()
+	p.open--
+	if cnt != nil {
+		*cnt++
+	}
+	p.mu.Unlock()
+	conn.Close()
+}
+
+// putConn returns session to the pool.
+func (p *connPool) putConn(conn *pooledConn) {
+	now := time.Now()
+	conn.returned = now
+	p.mu.Lock()
+	cnt := p.expired(conn, now)
+	p.mu.Unlock()
+	if cnt != nil {
+		p.closeConn(conn, cnt)
+	} else if !p.pushIdle(conn) {
+		p.closeConn(conn, nil)
+	}
+	p.releaseToken()
+}
+
+// discard closes a broken session and frees its place in the pool.
+func (p *connPool) discard(conn *pooledConn) {
+	if conn != nil {
+		p.closeConn(conn, &p.brokenClosed)
+	}
+	p.releaseToken()
+}
+
+// runCleaner periodically closes expired idle sessions until stop channel is closed.
+func (p *connPool) runCleaner(stop chan struct{}) {
+	dt := p.maxIdleTime
+	if p.maxLifetime > 0 && (dt <= 0 || p.maxLifetime < dt) {
+		dt = p.maxLifetime
+	}
+	if dt < time.Second {
+		dt = time.Second
+	}
+	t := time.NewTicker(dt)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		p.cleanIdle()
+	}
+}
+
+// cleanIdle closes expired idle sessions.
+func (p *connPool) cleanIdle() {
+	now := time.Now()
+	var closed []*pooledConn
+	p.mu.Lock()
+	idle := p.idle[:0]
+	for _, conn := range p.idle {
+		if cnt := p.expired(conn, now); cnt != nil {
+			*cnt++
+			p.open--
+			closed = append(closed, conn)
+		} else {
+			idle = append(idle, conn)
+		}
+	}
+	for i := len(idle); i < len(p.idle); i++ {
+		p.idle[i] = nil
+	}
+	p.idle = idle
+	p.mu.Unlock()
+	for _, conn := range closed {
+		conn.Close()
+	}
+}
+
+// close closes all idle sessions and stops background cleaner. Sessions in use are closed when they are returned,
+// and no new sessions are given out.
+func (p *connPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.open -= len(idle)
+	if p.cleaner != nil {
+		close(p.cleaner)
+		p.cleaner = nil
+	}
+	p.mu.Unlock()
+	for _, conn := range idle {
+		conn.Close()
+	}
+}
+
+// stats returns pool statistics.
+func (p *connPool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		MaxOpen:           p.maxOpen,
+		Open:              p.open,
+		InUse:             p.open - len(p.idle),
+		Idle:              len(p.idle),
+		WaitCount:         p.waitCount,
+		WaitDuration:      p.waitDuration,
+		MaxIdleClosed:     p.idleClosed,
+		MaxLifetimeClosed: p.lifeClosed,
+		BrokenClosed:      p.brokenClosed,
+	}
+}
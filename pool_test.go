@@ -0,0 +1,173 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSession implements only the methods of DBSession used by the pool.
+type fakeSession struct {
+	DBSession
+	dead   bool
+	noPing bool
+	closed *int32
+}
+
+func (s *fakeSession) Alive() bool { return !s.dead }
+func (s *fakeSession) PingContext(ctx context.Context) error {
+	if s.dead || s.noPing {
+		return ErrClosedConnection
+	}
+	return nil
+}
+func (s *fakeSession) Close() error {
+	atomic.AddInt32(s.closed, 1)
+	return nil
+}
+
+func newFakePool(opts Options) (*connPool, *int32, *int32) {
+	var dialed, closed int32
+	p := newConnPool(opts, func(ctx context.Context) (DBSession, error) {
+		atomic.AddInt32(&dialed, 1)
+		return &fakeSession{closed: &closed}, nil
+	})
+	return p, &dialed, &closed
+}
+
+func TestConnPoolGetConnContextCancel(t *testing.T) {
+	p, _, _ := newFakePool(Options{MaxConnections: 1})
+	if _, err := p.getConn(); err != nil {
+		t.Fatal(err)
+	} // pool is exhausted now
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := p.getConnContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline error, got: %v", err)
+	}
+	if st := p.stats(); st.WaitCount != 1 || st.WaitDuration <= 0 || st.InUse != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestConnPoolDialErrorReleasesToken(t *testing.T) {
+	fail := true
+	p := newConnPool(Options{MaxConnections: 1}, func(ctx context.Context) (DBSession, error) {
+		if fail {
+			return nil, fmt.Errorf("dial failed")
+		}
+		return &fakeSession{closed: new(int32)}, nil
+	})
+	if _, err := p.getConn(); err == nil {
+		t.Fatal("expected dial error")
+	}
+	fail = false
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := p.getConnContext(ctx); err != nil {
+		t.Fatalf("token was not returned to the pool: %v", err)
+	}
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	p, dialed, _ := newFakePool(Options{MaxConnections: 2})
+	c1, _ := p.getConn()
+	c2, _ := p.getConn()
+	if st := p.stats(); st.Open != 2 || st.InUse != 2 || st.Idle != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	p.putConn(c1)
+	p.putConn(c2)
+	if st := p.stats(); st.Open != 2 || st.InUse != 0 || st.Idle != 2 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	c3, _ := p.getConn()
+	if c3 != c2 {
+		t.Fatal("most recently used session expected")
+	}
+	p.putConn(c3)
+	if n := atomic.LoadInt32(dialed); n != 2 {
+		t.Fatalf("expected 2 dials, got %d", n)
+	}
+}
+
+func TestConnPoolReplaceDead(t *testing.T) {
+	p, dialed, closed := newFakePool(Options{MaxConnections: 1})
+	c1, _ := p.getConn()
+	p.putConn(c1)
+	c1.DBSession.(*fakeSession).dead = true
+
+	c2, err := p.getConn()
+	if err != nil {
+		t.Fatal(err)
+	} else if c2 == c1 {
+		t.Fatal("dead session was reused")
+	}
+	p.putConn(c2)
+	if atomic.LoadInt32(dialed) != 2 || atomic.LoadInt32(closed) != 1 {
+		t.Fatal("dead session was not replaced")
+	}
+	if st := p.stats(); st.BrokenClosed != 1 || st.Open != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestConnPoolPingOnBorrow(t *testing.T) {
+	p, dialed, _ := newFakePool(Options{MaxConnections: 1, PingOnBorrow: true})
+	c1, _ := p.getConn()
+	p.putConn(c1)
+	c1.DBSession.(*fakeSession).noPing = true // connection looks alive, but server does not respond
+
+	c2, err := p.getConn()
+	if err != nil {
+		t.Fatal(err)
+	} else if c2 == c1 {
+		t.Fatal("broken session was reused")
+	}
+	p.putConn(c2)
+	if atomic.LoadInt32(dialed) != 2 {
+		t.Fatal("broken session was not replaced")
+	}
+}
+
+func TestConnPoolIdleAndLifetime(t *testing.T) {
+	p, _, closed := newFakePool(Options{MaxConnections: 2, MaxIdleTime: time.Minute, MaxLifetime: time.Hour})
+	defer p.close()
+	c1, _ := p.getConn()
+	c2, _ := p.getConn()
+	p.putConn(c1)
+	p.putConn(c2)
+
+	c1.returned = time.Now().Add(-2 * time.Minute)
+	c2.created = time.Now().Add(-2 * time.Hour)
+	p.cleanIdle()
+	if n := atomic.LoadInt32(closed); n != 2 {
+		t.Fatalf("expected 2 closed sessions, got %d", n)
+	}
+	if st := p.stats(); st.Open != 0 || st.Idle != 0 || st.MaxIdleClosed != 1 || st.MaxLifetimeClosed != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestConnPoolClose(t *testing.T) {
+	p, _, closed := newFakePool(Options{MaxConnections: 2, MaxIdleTime: time.Minute})
+	c1, _ := p.getConn()
+	c2, _ := p.getConn()
+	p.putConn(c1)
+	p.close()
+	if n := atomic.LoadInt32(closed); n != 1 {
+		t.Fatalf("idle session was not closed: %d", n)
+	}
+	p.putConn(c2)
+	if n := atomic.LoadInt32(closed); n != 2 {
+		t.Fatalf("session returned after close was not closed: %d", n)
+	} else if st := p.stats(); st.Open != 0 || st.Idle != 0 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	if _, err := p.getConn(); err != ErrClosedDatabase {
+		t.Fatalf("expected closed database error, got: %v", err)
+	}
+}
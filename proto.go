@@ -1,5 +1,7 @@
 package orient
 
+import "context"
+
 // Default protocols
 const (
 	ProtoBinary = "binary"
@@ -25,11 +27,12 @@ const (
 )
 
 var (
-	protos = make(map[string]func(addr string) (DBConnection, error))
+	protos = make(map[string]func(addr string, opts Options) (DBConnection, error))
 )
 
-// RegisterProto registers a new protocol for Dial command
-func RegisterProto(name string, dial func(addr string) (DBConnection, error)) {
+// RegisterProto registers a new protocol for Dial command.
+// Dial function receives client options, as passed to DialWithOptions.
+func RegisterProto(name string, dial func(addr string, opts Options) (DBConnection, error)) {
 	protos[name] = dial
 }
 
@@ -42,37 +45,74 @@ type ODatabase struct {
 
 // DBAdmin is a minimal interface for database management API implementation
 type DBAdmin interface {
-	DatabaseExists(name string, storageType StorageType) (bool, error)
-	CreateDatabase(name string, dbType DatabaseType, storageType StorageType) error
-	DropDatabase(name string, storageType StorageType) error
-	ListDatabases() (map[string]string, error)
+	DatabaseExistsContext(ctx context.Context, name string, storageType StorageType) (bool, error)
+	CreateDatabaseContext(ctx context.Context, name string, dbType DatabaseType, storageType StorageType) error
+	DropDatabaseContext(ctx context.Context, name string, storageType StorageType) error
+	ListDatabasesContext(ctx context.Context) (map[string]string, error)
+	ServerConfigContext(ctx context.Context) (map[string]string, error)
+	ServerConfigValueContext(ctx context.Context, key string) (string, error)
+	SetServerConfigContext(ctx context.Context, key, val string) error
+	ShutdownContext(ctx context.Context) error
+	FreezeDatabaseContext(ctx context.Context, name string, storageType StorageType) error
+	ReleaseDatabaseContext(ctx context.Context, name string, storageType StorageType) error
+	FreezeClusterContext(ctx context.Context, name string, clusterID int16, storageType StorageType) error
+	ReleaseClusterContext(ctx context.Context, name string, clusterID int16, storageType StorageType) error
 	Close() error
 }
 
 // DBSession is a minimal interface for database API implementation
 type DBSession interface {
 	Close() error
-	Size() (int64, error)
-	ReloadSchema() error
+	PingContext(ctx context.Context) error
+	SizeContext(ctx context.Context) (int64, error)
+	ReloadSchemaContext(ctx context.Context) error
+	StorageInfoContext(ctx context.Context) (*StorageInfo, error)
 	GetCurDB() *ODatabase
+	Token() SessionToken
+
+	AddClusterWithIDContext(ctx context.Context, clusterName string, id int16) (clusterID int16, err error)
+	DropClusterContext(ctx context.Context, clusterName string) (err error)
+	GetClusterDataRangeContext(ctx context.Context, clusterName string) (begin, end int64, err error)
+	ClustersCountContext(ctx context.Context, withDeleted bool, clusterNames ...string) (int64, error)
+	ClusterPositionsContext(ctx context.Context, clusterName string, pos int64, op PositionsOp) ([]PhysicalPosition, error)
+
+	CreateRecordContext(ctx context.Context, rec ORecord) (err error)
+	DeleteRecordByRIDContext(ctx context.Context, rid RID, recVersion int) error
+	RecordMetadataContext(ctx context.Context, rid RID) (version int, err error)
+	HideRecordContext(ctx context.Context, rid RID) error
+	CleanOutRecordContext(ctx context.Context, rid RID, recVersion int) error
+	GetRecordByRIDContext(ctx context.Context, rid RID, fetchPlan FetchPlan, ignoreCache bool) (rec ORecord, err error)
+	UpdateRecordContext(ctx context.Context, rec ORecord) error
+	CountRecordsContext(ctx context.Context) (int64, error)
+
+	// CommitContext sends transaction operations to the server and updates RIDs and versions of records.
+	CommitContext(ctx context.Context, txID int, ops []TxOp) error
 
-	AddClusterWithID(clusterName string, id int16) (clusterID int16, err error)
-	DropCluster(clusterName string) (err error)
-	GetClusterDataRange(clusterName string) (begin, end int64, err error)
-	ClustersCount(withDeleted bool, clusterNames ...string) (int64, error)
+	CommandContext(ctx context.Context, cmd CustomSerializable) (result interface{}, err error)
 
-	CreateRecord(rec ORecord) (err error)
-	DeleteRecordByRID(rid RID, recVersion int) error
-	GetRecordByRID(rid RID, fetchPlan FetchPlan, ignoreCache bool) (rec ORecord, err error)
-	UpdateRecord(rec ORecord) error
-	CountRecords() (int64, error)
+	RidBagFirstKeyContext(ctx context.Context, ptr BonsaiPointer) (RID, error)
+	RidBagEntriesMajorContext(ctx context.Context, ptr BonsaiPointer, key RID, inclusive bool, pageSize int) ([]RidBagEntry, error)
+	RidBagSizeContext(ctx context.Context, ptr BonsaiPointer, changes RidBagChanges) (int, error)
 
-	Command(cmd CustomSerializable) (result interface{}, err error)
+	LiveQueryContext(ctx context.Context, cmd CustomSerializable, fnc func(LiveEvent)) (token int, err error)
+	LiveUnsubscribeContext(ctx context.Context, token int) error
+}
+
+// CommandStream is a result of a command that is decoded incrementally from the connection.
+// Stream is bound to the session that created it, and session can't be used until stream is closed.
+type CommandStream interface {
+	// Value returns the result of a command if it's not a collection of records. Stream is closed in this case.
+	Value() (result interface{}, ok bool)
+	// NextContext returns next record of the result. It returns io.EOF after the last record.
+	NextContext(ctx context.Context) (OIdentifiable, error)
+	// Close reads and discards remaining records and releases the session.
+	Close() error
 }
 
 // DBConnection is a minimal interface for OrientDB server API implementation
 type DBConnection interface {
-	Auth(user, pass string) (DBAdmin, error)
-	Open(name string, dbType DatabaseType, user, pass string) (DBSession, error)
+	AuthContext(ctx context.Context, user, pass string) (DBAdmin, error)
+	OpenContext(ctx context.Context, name string, dbType DatabaseType, user, pass string) (DBSession, error)
+	OpenTokenContext(ctx context.Context, name string, dbType DatabaseType, tok SessionToken) (DBSession, error)
 	Close() error
 }
@@ -0,0 +1,14 @@
+package orient
+
+import "context"
+
+// Query runs SQL query with Command. Records are read from the connection as Next is called, so it's safe
+// to scan large classes. Results must be closed to release the session.
+func (db *Database) Query(sql string, params ...interface{}) Results {
+	return db.QueryContext(context.Background(), sql, params...)
+}
+
+// QueryContext is like Query, but allows to cancel the request with a context.
+func (db *Database) QueryContext(ctx context.Context, sql string, params ...interface{}) Results {
+	return db.CommandContext(ctx, NewSQLQuery(sql, params...))
+}
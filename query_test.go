@@ -0,0 +1,106 @@
+package orient
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+type fakeStream struct {
+	recs   []OIdentifiable
+	closed bool
+}
+
+func (s *fakeStream) Value() (interface{}, bool) { return nil, false }
+
+func (s *fakeStream) NextContext(ctx context.Context) (OIdentifiable, error) {
+	if len(s.recs) == 0 {
+		return nil, io.EOF
+	}
+	rec := s.recs[0]
+	s.recs = s.recs[1:]
+	return rec, nil
+}
+
+func (s *fakeStream) Close() error {
+	s.recs = nil
+	s.closed = true
+	return nil
+}
+
+type streamSession struct {
+	testSession
+	stream *fakeStream
+}
+
+func (s *streamSession) CommandStreamContext(ctx context.Context, cmd CustomSerializable) (CommandStream, error) {
+	return s.stream, nil
+}
+
+func (s *streamSession) CommandContext(ctx context.Context, cmd CustomSerializable) (interface{}, error) {
+	recs := s.stream.recs
+	s.stream.recs = nil
+	return recs, nil
+}
+
+func TestCommandResultsStream(t *testing.T) {
+	st := &fakeStream{recs: []OIdentifiable{
+		RID{ClusterID: 1, ClusterPos: 1}, RID{ClusterID: 1, ClusterPos: 2}, RID{ClusterID: 1, ClusterPos: 3},
+	}}
+	db := newTestDB(t, func() DBSession { return &streamSession{stream: st} })
+	r := db.Command(NewSQLQuery("SELECT FROM V"))
+	var rid RID
+	if !r.Next(&rid) {
+		t.Fatal(r.Err())
+	} else if rid.ClusterPos != 1 || len(st.recs) != 2 {
+		t.Fatalf("records were not read one by one: %v, %d left", rid, len(st.recs))
+	} else if db.Stats().InUse != 1 {
+		t.Fatal("session was released before results were closed")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !st.closed {
+		t.Fatal("stream was not closed")
+	} else if db.Stats().InUse != 0 {
+		t.Fatal("session was not released")
+	}
+
+	st.recs, st.closed = []OIdentifiable{RID{ClusterID: 1, ClusterPos: 4}, RID{ClusterID: 1, ClusterPos: 5}}, false
+	var rids []RID
+	if err := db.Command(NewSQLQuery("SELECT FROM V")).All(&rids); err != nil {
+		t.Fatal(err)
+	} else if len(rids) != 2 || rids[1].ClusterPos != 5 {
+		t.Fatalf("unexpected results: %v", rids)
+	} else if db.Stats().InUse != 0 {
+		t.Fatal("session was not released")
+	}
+}
+
+func TestCommandResultsStreamErr(t *testing.T) {
+	st := &fakeStream{recs: []OIdentifiable{RID{ClusterID: 1, ClusterPos: 1}, RID{ClusterID: 1, ClusterPos: 2}}}
+	db := newTestDB(t, func() DBSession { return &streamSession{stream: st} })
+	r := db.Command(NewSQLQuery("SELECT FROM V"))
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	} else if db.Stats().InUse != 0 {
+		t.Fatal("session was not released by Err")
+	}
+	var rids []RID
+	if err := r.All(&rids); err != nil {
+		t.Fatal(err)
+	} else if len(rids) != 2 || rids[1].ClusterPos != 2 {
+		t.Fatalf("records read by Err were lost: %v", rids)
+	}
+
+	// other commands are not streamed
+	st.recs, st.closed = []OIdentifiable{RID{ClusterID: 1, ClusterPos: 3}}, false
+	r = db.Command(NewSQLCommand("UPDATE V SET a = 1 RETURN AFTER"))
+	if db.Stats().InUse != 0 {
+		t.Fatal("session is held by command results")
+	} else if err := r.All(&rids); err != nil {
+		t.Fatal(err)
+	} else if len(rids) != 1 || rids[0].ClusterPos != 3 {
+		t.Fatalf("unexpected results: %v", rids)
+	}
+}
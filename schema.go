@@ -0,0 +1,114 @@
+package orient
+
+import (
+	"context"
+	"regexp"
+	"sync/atomic"
+)
+
+// reSchemaCommand matches SQL statements that change database schema. It's also applied to scripts,
+// so a false match only causes an additional schema reload.
+var reSchemaCommand = regexp.MustCompile(`(?i)\b(create|alter|drop)\s+(class|property)\b`)
+
+// isSchemaCommand checks if command changes database schema.
+func isSchemaCommand(cmd OCommandRequestText) bool {
+	return reSchemaCommand.MatchString(cmd.GetText())
+}
+
+// OnSchemaChange registers a function that is called each time the schema of the database is changed with a DDL
+// command or is reloaded with ReloadSchema. Function receives a copy of the schema loaded after the change,
+// so it can be kept and used after the function returns.
+//
+// Functions are called synchronously from the goroutine that executed the command, thus they should not block.
+func (db *Database) OnSchemaChange(fnc func(schema *ODatabase)) {
+	db.schemaMu.Lock()
+	db.schemaHooks = append(db.schemaHooks, fnc)
+	db.schemaMu.Unlock()
+}
+
+// schemaChanged reloads the schema on a given session, marks schema of all other pooled sessions as stale
+// and notifies schema hooks.
+func (db *Database) schemaChanged(ctx context.Context, conn DBSession) error {
+	gen := atomic.AddInt64(&db.schemaGen, 1)
+	if err := conn.ReloadSchemaContext(ctx); err != nil {
+		return err
+	}
+	if pc, ok := conn.(*pooledConn); ok {
+		pc.schemaGen = gen
+	}
+	db.schemaMu.Lock()
+	hooks := db.schemaHooks
+	db.schemaMu.Unlock()
+	if len(hooks) == 0 {
+		return nil
+	}
+	cur := conn.GetCurDB()
+	for _, fnc := range hooks {
+		fnc(copySchema(cur)) // schema of the session is replaced on the next reload
+	}
+	return nil
+}
+
+// copySchema returns a deep copy of database schema.
+func copySchema(d *ODatabase) *ODatabase {
+	if d == nil {
+		return nil
+	}
+	out := *d
+	if d.Classes != nil {
+		out.Classes = make(map[string]*OClass, len(d.Classes))
+		for name, cl := range d.Classes {
+			out.Classes[name] = copyClass(cl)
+		}
+	}
+	return &out
+}
+
+func copyClass(cl *OClass) *OClass {
+	if cl == nil {
+		return nil
+	}
+	out := *cl
+	out.ClusterIds = append([]int32(nil), cl.ClusterIds...)
+	out.CustomFields = copyStringMap(cl.CustomFields)
+	if cl.Properties != nil {
+		out.Properties = make(map[string]*OProperty, len(cl.Properties))
+		for name, prop := range cl.Properties {
+			if prop != nil {
+				p := *prop
+				p.CustomFields = copyStringMap(prop.CustomFields)
+				prop = &p
+			}
+			out.Properties[name] = prop
+		}
+	}
+	return &out
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// syncSchema reloads the schema of a pooled session if it was changed since the session has loaded it.
+// New sessions load the schema when they are opened, so they are considered up to date.
+func (db *Database) syncSchema(ctx context.Context, conn *pooledConn) error {
+	gen := atomic.LoadInt64(&db.schemaGen)
+	if conn.schemaGen < 0 {
+		conn.schemaGen = gen
+		return nil
+	} else if conn.schemaGen >= gen {
+		return nil
+	}
+	if err := conn.ReloadSchemaContext(ctx); err != nil {
+		return err
+	}
+	conn.schemaGen = gen
+	return nil
+}
@@ -0,0 +1,122 @@
+package orient
+
+import (
+	"context"
+	"testing"
+)
+
+type schemaSession struct {
+	testSession
+	reloads int
+	schema  *ODatabase
+}
+
+func (s *schemaSession) ReloadSchemaContext(ctx context.Context) error {
+	s.reloads++
+	s.schema = &ODatabase{Name: "db", Classes: map[string]*OClass{
+		"V": {Name: "V", Properties: map[string]*OProperty{"name": {Name: "name"}}},
+	}}
+	return nil
+}
+
+func (s *schemaSession) CommandContext(ctx context.Context, cmd CustomSerializable) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *schemaSession) GetCurDB() *ODatabase {
+	return s.schema
+}
+
+func TestSchemaReloadOnDDL(t *testing.T) {
+	var sessions []*schemaSession
+	db := newTestDB(t, func() DBSession {
+		s := &schemaSession{}
+		sessions = append(sessions, s)
+		return s
+	})
+	var (
+		changes int
+		last    *ODatabase
+	)
+	db.OnSchemaChange(func(schema *ODatabase) {
+		if schema == nil || schema.Name != "db" {
+			t.Errorf("unexpected schema: %+v", schema)
+		}
+		changes++
+		last = schema
+	})
+
+	// open two sessions, so one of them stays idle while the command is executed
+	ctx := context.Background()
+	_, release1, err := db.getConn(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, release2, err := db.getConn(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release1(nil)
+	release2(nil)
+
+	if err = db.Command(NewSQLCommand("SELECT FROM V")).Err(); err != nil {
+		t.Fatal(err)
+	} else if changes != 0 {
+		t.Fatal("schema hook called for a query")
+	}
+	if err = db.Command(NewSQLCommand("CREATE PROPERTY V.name STRING")).Err(); err != nil {
+		t.Fatal(err)
+	} else if changes != 1 {
+		t.Fatalf("schema hook was called %d times", changes)
+	}
+	reloads := func() (n int) {
+		for _, s := range sessions {
+			n += s.reloads
+		}
+		return n
+	}
+	if n := reloads(); n != 1 {
+		t.Fatalf("expected one reload, got %d", n)
+	}
+	for _, s := range sessions {
+		if s.schema != nil {
+			if last == s.schema || last.Classes["V"] == s.schema.Classes["V"] {
+				t.Fatal("schema of the session was passed to the hook")
+			}
+			s.schema.Classes["V"].Properties["name"].Name = "changed"
+		}
+	}
+	if name := last.Classes["V"].Properties["name"].Name; name != "name" {
+		t.Fatalf("schema passed to the hook was changed: %q", name)
+	}
+	// take both sessions again; stale one should reload the schema, the other one is up to date
+	_, release1, err = db.getConn(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, release2, err = db.getConn(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release1(nil)
+	release2(nil)
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	} else if sessions[0].reloads != 1 || sessions[1].reloads != 1 {
+		t.Fatalf("each session should reload schema once: %d, %d", sessions[0].reloads, sessions[1].reloads)
+	}
+}
+
+func TestIsSchemaCommand(t *testing.T) {
+	for sql, exp := range map[string]bool{
+		"CREATE CLASS Foo EXTENDS V":             true,
+		"alter property Foo.name MANDATORY true": true,
+		"BEGIN;\nDROP CLASS Foo;\nCOMMIT;":       true,
+		"CREATE VERTEX Foo SET name = 'a'":       false,
+		"SELECT FROM Foo":                        false,
+	} {
+		if got := isSchemaCommand(NewSQLCommand(sql)); got != exp {
+			t.Errorf("%q: expected %v", sql, exp)
+		}
+	}
+}
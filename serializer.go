@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 	"io"
+	"time"
 )
 
 // ErrTypeSerialization represent serialization/deserialization error
@@ -60,6 +61,14 @@ type RecordSerializer interface {
 	SetGlobalPropertyFunc(fnc GlobalPropertyFunc)
 }
 
+// TimeZoneSetter is implemented by record serializers that convert DATE and DATETIME values
+// using the time zone of the database.
+type TimeZoneSetter interface {
+	// SetTimeZone sets time zone of the database. DATETIME values are returned in this location,
+	// and DATE values are midnights of the corresponding day in this location. Nil means local time zone.
+	SetTimeZone(loc *time.Location)
+}
+
 // RegisterRecordFormat registers RecordSerializer with a given class name
 func RegisterRecordFormat(name string, fnc func() RecordSerializer) {
 	recordFormats[name] = fnc
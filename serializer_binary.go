@@ -31,6 +31,7 @@ var nilRID = RID{ClusterID: -2, ClusterPos: -1}
 var (
 	binaryFormatVerions = []func() binaryRecordFormat{
 		func() binaryRecordFormat { return &binaryRecordFormatV0{} },
+		func() binaryRecordFormat { return &binaryRecordFormatV1{} },
 	}
 )
 
@@ -39,16 +40,25 @@ type binaryRecordFormat interface {
 	Deserialize(doc *Document, r *rw.ReadSeeker) error
 
 	SetGlobalPropertyFunc(fnc GlobalPropertyFunc)
+	SetTimeZone(loc *time.Location)
 }
 
+var _ TimeZoneSetter = (*BinaryRecordFormat)(nil)
+
 type BinaryRecordFormat struct {
 	fnc GlobalPropertyFunc
+	loc *time.Location
 }
 
 func (BinaryRecordFormat) String() string { return binaryFormatName }
 func (f *BinaryRecordFormat) SetGlobalPropertyFunc(fnc GlobalPropertyFunc) {
 	f.fnc = fnc
 }
+
+// SetTimeZone sets time zone of the database (see TimeZoneSetter).
+func (f *BinaryRecordFormat) SetTimeZone(loc *time.Location) {
+	f.loc = loc
+}
 func (f BinaryRecordFormat) ToStream(w io.Writer, rec ORecord) error {
 	doc, ok := rec.(*Document)
 	if !ok {
@@ -61,6 +71,7 @@ func (f BinaryRecordFormat) ToStream(w io.Writer, rec ORecord) error {
 	// TODO: apply partial serialization to prevent infinite recursion of records
 	ser := binaryFormatVerions[binaryFormatCurrentVersion]()
 	ser.SetGlobalPropertyFunc(f.fnc)
+	ser.SetTimeZone(f.loc)
 	if err := bw.Err(); err != nil {
 		return err
 	}
@@ -84,8 +95,13 @@ func (f BinaryRecordFormat) FromStream(data []byte) (out ORecord, err error) {
 
 	// TODO: support partial deserialization (only certain fields)
 
+	if int(vers) >= len(binaryFormatVerions) {
+		err = fmt.Errorf("unsupported binary record format version: %d", vers)
+		return
+	}
 	ser := binaryFormatVerions[vers]()
 	ser.SetGlobalPropertyFunc(f.fnc)
+	ser.SetTimeZone(f.loc)
 	doc := NewEmptyDocument()
 	if err = ser.Deserialize(doc, br); err != nil {
 		return
@@ -100,11 +116,16 @@ type globalProperty struct {
 
 type binaryRecordFormatV0 struct {
 	getGlobalPropertyFunc GlobalPropertyFunc
+	loc                   *time.Location // database time zone; nil means local time zone
 }
 
 func (f *binaryRecordFormatV0) SetGlobalPropertyFunc(fnc GlobalPropertyFunc) {
 	f.getGlobalPropertyFunc = fnc
 }
+
+func (f *binaryRecordFormatV0) SetTimeZone(loc *time.Location) {
+	f.loc = loc
+}
 func (f binaryRecordFormatV0) getGlobalProperty(doc *Document, leng int) OGlobalProperty {
 	id := (leng * -1) - 1
 
@@ -290,12 +311,8 @@ func (f binaryRecordFormatV0) readEmbeddedMap(r *rw.ReadSeeker, doc *Document) (
 		return nil, r.Err()
 	}
 	last := int64(0)
-	type entry struct {
-		Key interface{}
-		Val interface{}
-	}
 	var (
-		result     = make([]entry, 0, size) // TODO: can't return just this slice, need some public implementation
+		result     = make([]embeddedMapEntry, 0, size) // TODO: can't return just this slice, need some public implementation
 		keyTypes   = make(map[OType]bool, 1)
 		valueTypes = make(map[OType]bool, 2)
 	)
@@ -320,9 +337,9 @@ func (f binaryRecordFormatV0) readEmbeddedMap(r *rw.ReadSeeker, doc *Document) (
 				last = off
 			}
 			r.Seek(headerCursor, 0)
-			result = append(result, entry{Key: key, Val: value})
+			result = append(result, embeddedMapEntry{Key: key, Val: value})
 		} else {
-			result = append(result, entry{Key: key, Val: nil})
+			result = append(result, embeddedMapEntry{Key: key, Val: nil})
 		}
 	}
 	if off, _ := r.Seek(0, 1); last > off {
@@ -332,6 +349,16 @@ func (f binaryRecordFormatV0) readEmbeddedMap(r *rw.ReadSeeker, doc *Document) (
 		return nil, err
 	}
 	//fmt.Printf("embedded map: types: %+v, vals: %+v\n", keyTypes, valueTypes)
+	return makeEmbeddedMap(result, keyTypes, valueTypes), nil
+}
+
+type embeddedMapEntry struct {
+	Key interface{}
+	Val interface{}
+}
+
+// makeEmbeddedMap converts a list of map entries to a typed map, if key and value types are known.
+func makeEmbeddedMap(result []embeddedMapEntry, keyTypes, valueTypes map[OType]bool) interface{} {
 	var (
 		keyType reflect.Type
 		valType reflect.Type = UNKNOWN.ReflectType()
@@ -339,7 +366,7 @@ func (f binaryRecordFormatV0) readEmbeddedMap(r *rw.ReadSeeker, doc *Document) (
 	if len(keyTypes) == 1 {
 		for k, _ := range keyTypes {
 			if k == UNKNOWN {
-				return result, nil
+				return result
 			}
 			keyType = k.ReflectType()
 			break
@@ -363,7 +390,7 @@ func (f binaryRecordFormatV0) readEmbeddedMap(r *rw.ReadSeeker, doc *Document) (
 		}
 		rv.SetMapIndex(reflect.ValueOf(kv.Key), value)
 	}
-	return rv.Interface(), nil
+	return rv.Interface()
 }
 func (f binaryRecordFormatV0) readSingleValue(r *rw.ReadSeeker, valueType OType, doc *Document) (value interface{}, err error) {
 	defer func() {
@@ -394,15 +421,22 @@ func (f binaryRecordFormatV0) readSingleValue(r *rw.ReadSeeker, valueType OType,
 		value = f.readByte(r) == 1
 	case DATETIME:
 		longTime := r.ReadVarint()
-		value = time.Unix(longTime/1000, (longTime%1000)*1e6)
+		t := time.Unix(longTime/1000, (longTime%1000)*1e6)
+		if f.loc != nil {
+			t = t.In(f.loc)
+		}
+		value = t
 	case DATE:
 		//	long savedTime = OVarIntSerializer.readAsLong(bytes) * MILLISEC_PER_DAY;
 		//	int offset = ODateHelper.getDatabaseTimeZone().getOffset(savedTime);
 		//	value = new Date(savedTime - offset);
 		savedTime := r.ReadVarint() * millisecPerDay
-		t := time.Unix(savedTime/1000, (savedTime%1000)*1e6) //.UTC().Local()
-		//		_, offset := t.Zone()
-		//		value = t.Add(-time.Duration(offset) * time.Second)
+		t := time.Unix(savedTime/1000, (savedTime%1000)*1e6)
+		if f.loc != nil {
+			// date is stored as a number of days in UTC, while the value is a midnight in database time zone
+			t = t.UTC()
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, f.loc)
+		}
 		value = t
 	case EMBEDDED:
 		doc2 := NewEmptyDocument()
@@ -730,10 +764,13 @@ func (f binaryRecordFormatV0) writeSingleValue(w *rw.Writer, off int, o interfac
 			w.WriteVarint(t)
 		} else {
 			t := o.(time.Time)
+			if f.loc != nil {
+				// int offset = ODateHelper.getDatabaseTimeZone().getOffset(dateValue)
+				t = t.In(f.loc)
+				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			}
 			it := t.Unix()*1000 + int64(t.Nanosecond())/1e6
-			var offset int64
-			// TODO: int offset = ODateHelper.getDatabaseTimeZone().getOffset(dateValue)
-			w.WriteVarint((it + offset) / millisecPerDay)
+			w.WriteVarint(it / millisecPerDay)
 		}
 	case EMBEDDED:
 		var edoc *Document
@@ -827,6 +864,9 @@ func (binaryRecordFormatV0) getLinkedType(doc *Document, tp OType, key string) O
 	if tp != EMBEDDEDLIST && tp != EMBEDDEDSET && tp != EMBEDDEDMAP {
 		return UNKNOWN
 	}
+	if fld := doc.fields[key]; fld != nil && fld.hasLinkedType {
+		return fld.linkedType
+	}
 	// TODO: OClass clazz = ODocumentInternal.getImmutableSchemaClass(document); if (clazz != null) ...
 	return UNKNOWN
 }
@@ -210,6 +210,39 @@ func TestSerializeDatetimeV0(t *testing.T) {
 	}
 }
 
+func TestSerializeDateTimeZone(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*3600)
+	f := binaryRecordFormatV0{loc: loc}
+
+	// midnight in database time zone is a previous day in UTC
+	buf := bytes.NewBuffer(nil)
+	date := time.Date(2016, 3, 15, 0, 0, 0, 0, loc)
+	if err := f.writeSingleValue(rw.NewWriter(buf), 0, date, DATE, UNKNOWN); err != nil {
+		t.Fatal(err)
+	}
+	if days := rw.NewReadSeeker(bytes.NewReader(buf.Bytes())).ReadVarint(); days != time.Date(2016, 3, 15, 0, 0, 0, 0, time.UTC).Unix()/86400 {
+		t.Fatalf("unexpected number of days: %d", days)
+	}
+	out, err := f.readSingleValue(rw.NewReadSeeker(bytes.NewReader(buf.Bytes())), DATE, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if !out.(time.Time).Equal(date) || out.(time.Time).Location() != loc {
+		t.Fatalf("values differs: %v != %v", out, date)
+	}
+
+	buf.Reset()
+	dt := time.Date(2016, 3, 15, 23, 30, 0, 0, time.UTC)
+	if err = f.writeSingleValue(rw.NewWriter(buf), 0, dt, DATETIME, UNKNOWN); err != nil {
+		t.Fatal(err)
+	}
+	out, err = f.readSingleValue(rw.NewReadSeeker(bytes.NewReader(buf.Bytes())), DATETIME, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if val := out.(time.Time); !val.Equal(dt) || val.Location() != loc || val.Day() != 16 {
+		t.Fatalf("unexpected datetime: %v", val)
+	}
+}
+
 func testDocumentToStruct(t *testing.T, dataBase64 string) {
 	data, err := base64.StdEncoding.DecodeString(dataBase64)
 	if err != nil {
@@ -248,3 +281,55 @@ func TestDocumentInnerStruct(t *testing.T) {
 func TestDocumentInnerMapToStruct(t *testing.T) {
 	testDocumentToStruct(t, "AAJWBk9uZQAAABgMCklubmVyAAAAKAoAAgcITmFtZQAAACQHBm9uZQQXDAIHCE5hbWUAAAA3BwZvbmUMAgcITmFtZQAAAEgHBnR3bw==")
 }
+
+func TestDeserializeRecordDataV1(t *testing.T) {
+	var values, header bytes.Buffer
+	vw, hw := rw.NewWriter(&values), rw.NewWriter(&header)
+	field := func(name string, tp OType, val func()) {
+		start := values.Len()
+		val()
+		hw.WriteStringVarint(name)
+		hw.WriteVarint(int64(values.Len() - start))
+		hw.WriteByte(byte(tp))
+	}
+	field("name", STRING, func() { vw.WriteStringVarint("Linus") })
+	field("nick", STRING, func() {})
+	field("tags", EMBEDDEDLIST, func() {
+		vw.WriteVarint(2)
+		vw.WriteByte(byte(INTEGER))
+		vw.WriteVarint(15)
+		vw.WriteByte(byte(UNKNOWN))
+	})
+	field("attrs", EMBEDDEDMAP, func() {
+		vw.WriteVarint(1)
+		vw.WriteByte(byte(STRING))
+		vw.WriteStringVarint("color")
+		vw.WriteByte(byte(STRING))
+		vw.WriteStringVarint("red")
+	})
+
+	var buf bytes.Buffer
+	w := rw.NewWriter(&buf)
+	w.WriteByte(1)
+	w.WriteStringVarint("Cat")
+	w.WriteVarint(int64(header.Len()))
+	w.WriteRawBytes(header.Bytes())
+	w.WriteRawBytes(values.Bytes())
+
+	rec, err := (&BinaryRecordFormat{}).FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := rec.(*Document)
+	if doc.ClassName() != "Cat" {
+		t.Fatalf("wrong class name: %q", doc.ClassName())
+	} else if v := doc.GetField("name").Value; v != "Linus" {
+		t.Fatalf("wrong name: %v", v)
+	} else if v := doc.GetField("nick").Value; v != nil {
+		t.Fatalf("expected nil, got: %v", v)
+	} else if v := doc.GetField("tags").Value; !reflect.DeepEqual(v, []interface{}{int32(15), nil}) {
+		t.Fatalf("wrong list: %#v", v)
+	} else if v := doc.GetField("attrs").Value; !reflect.DeepEqual(v, map[string]string{"color": "red"}) {
+		t.Fatalf("wrong map: %#v", v)
+	}
+}
@@ -0,0 +1,158 @@
+package orient
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+// binaryRecordFormatV1 is a version of binary record format used by OrientDB 3.0.
+//
+// It differs from V0 in a few ways: document header starts with it's length and stores sizes of values
+// instead of their positions, embedded maps are stored without value pointers and embedded collections
+// do not store a common type of items. Null values are marked with a type of -1 (UNKNOWN in this driver).
+//
+// Only deserialization is supported, since servers still accept V0 records.
+type binaryRecordFormatV1 struct {
+	binaryRecordFormatV0
+}
+
+func (f binaryRecordFormatV1) Serialize(doc *Document, w io.Writer, off int, classOnly bool) error {
+	return fmt.Errorf("serialization to binary record format v1 is not supported")
+}
+
+func (f binaryRecordFormatV1) Deserialize(doc *Document, r *rw.ReadSeeker) error {
+	className := f.readString(r)
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if len(className) != 0 {
+		doc.FillClassNameIfNeeded(className)
+	}
+
+	headerLen := r.ReadVarint()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	headerStart, _ := r.Seek(0, 1)
+	valuesStart := headerStart + headerLen
+	valuePos := valuesStart // values are stored in the same order as header entries
+	for {
+		if cur, _ := r.Seek(0, 1); cur >= valuesStart {
+			break
+		}
+		var (
+			fieldName string
+			valueLen  int64
+			valueType OType
+		)
+		leng := int(r.ReadVarint())
+		if err := r.Err(); err != nil {
+			return err
+		}
+		if leng > 0 {
+			// PARSE FIELD NAME
+			fieldNameBytes := make([]byte, leng)
+			r.ReadRawBytes(fieldNameBytes)
+			fieldName = string(fieldNameBytes)
+			valueLen = r.ReadVarint()
+			valueType = f.readOType(r)
+		} else if leng < 0 {
+			// LOAD GLOBAL PROPERTY BY ID
+			prop := f.getGlobalProperty(doc, leng)
+			fieldName = prop.Name
+			valueLen = r.ReadVarint()
+			if prop.Type != ANY {
+				valueType = prop.Type
+			} else {
+				valueType = f.readOType(r)
+			}
+		} else {
+			return fmt.Errorf("invalid field entry in record header")
+		}
+		pos := valuePos
+		valuePos += valueLen
+
+		if doc.RawContainsField(fieldName) {
+			continue
+		}
+		if valueLen == 0 {
+			doc.RawSetField(fieldName, nil, UNKNOWN)
+			continue
+		}
+		headerCursor, _ := r.Seek(0, 1)
+		r.Seek(pos, 0)
+		value, err := f.readSingleValue(r, valueType, doc)
+		if err != nil {
+			return err
+		}
+		r.Seek(headerCursor, 0)
+		doc.RawSetField(fieldName, value, valueType)
+	}
+	r.Seek(valuePos, 0)
+	return r.Err()
+}
+
+func (f binaryRecordFormatV1) readEmbeddedCollection(r *rw.ReadSeeker, doc *Document) ([]interface{}, error) {
+	n := int(r.ReadVarint())
+	out := make([]interface{}, n)
+	var err error
+	for i := range out {
+		if itemType := f.readOType(r); itemType != UNKNOWN && itemType != ANY {
+			out[i], err = f.readSingleValue(r, itemType, doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, r.Err()
+}
+
+func (f binaryRecordFormatV1) readEmbeddedMap(r *rw.ReadSeeker, doc *Document) (interface{}, error) {
+	size := int(r.ReadVarint())
+	if size == 0 {
+		return nil, r.Err()
+	}
+	var (
+		result     = make([]embeddedMapEntry, 0, size)
+		keyTypes   = make(map[OType]bool, 1)
+		valueTypes = make(map[OType]bool, 2)
+	)
+	for i := 0; i < size; i++ {
+		keyType := f.readOType(r)
+		key, err := f.readSingleValue(r, keyType, doc)
+		if err != nil {
+			return nil, err
+		}
+		keyTypes[keyType] = true
+		var value interface{}
+		if valueType := f.readOType(r); valueType != UNKNOWN {
+			valueTypes[valueType] = true
+			if value, err = f.readSingleValue(r, valueType, doc); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, embeddedMapEntry{Key: key, Val: value})
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return makeEmbeddedMap(result, keyTypes, valueTypes), nil
+}
+
+func (f binaryRecordFormatV1) readSingleValue(r *rw.ReadSeeker, valueType OType, doc *Document) (value interface{}, err error) {
+	switch valueType {
+	case EMBEDDED:
+		doc2 := NewEmptyDocument()
+		if err = f.Deserialize(doc2, r); err != nil {
+			return nil, err
+		}
+		return doc2, nil
+	case EMBEDDEDSET, EMBEDDEDLIST:
+		return f.readEmbeddedCollection(r, doc)
+	case EMBEDDEDMAP:
+		return f.readEmbeddedMap(r, doc)
+	}
+	return f.binaryRecordFormatV0.readSingleValue(r, valueType, doc)
+}
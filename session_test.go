@@ -0,0 +1,24 @@
+package orient
+
+import (
+	"context"
+	"testing"
+)
+
+// testSession is a base for fake sessions. Types embedding it override the methods used by a test;
+// other methods of DBSession panic.
+type testSession struct {
+	DBSession
+}
+
+func (testSession) Token() SessionToken { return SessionToken{} }
+
+func (testSession) Close() error { return nil }
+
+// newTestDB returns a database that gets sessions from newSess. Database is closed at the end of the test.
+func newTestDB(t testing.TB, newSess func() DBSession) *Database {
+	db := &Database{cli: &Client{}}
+	db.pool = newConnPool(Options{}, func(ctx context.Context) (DBSession, error) { return newSess(), nil })
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -1,32 +1,66 @@
 package orient
 
-/*
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
-	"io"
-	"net"
+	"net/url"
 	"regexp"
-	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Driver name for database/sql package
-const DriverNameSQL = "orient"
+var dsnRx = regexp.MustCompile(`([^@]+)@([^:]+):([^/]+)/(.+)`)
 
 var (
-	_ driver.Driver  = (*orientDriver)(nil)
-	_ driver.Conn    = (*Database)(nil)
-	_ driver.Execer  = (*Database)(nil)
-	_ driver.Queryer = (*Database)(nil)
+	tlsConfigsMu sync.RWMutex
+	tlsConfigs   = make(map[string]*tls.Config)
 )
 
-var dsnRx = regexp.MustCompile(`([^@]+)@([^:]+):([^/]+)/(.+)`)
+// RegisterTLSConfig registers a custom TLS config to be used with DialDSN. The config is selected by the "tls"
+// parameter of DSN:
+//   admin@admin:127.0.0.1/db?tls=custom
+//
+// Values "true" and "skip-verify" are reserved: the first one enables TLS with default config,
+// and the second one disables server certificate verification.
+func RegisterTLSConfig(key string, config *tls.Config) error {
+	switch key {
+	case "", "true", "false", "skip-verify":
+		return fmt.Errorf("orientgo: TLS config key %q is reserved", key)
+	}
+	tlsConfigsMu.Lock()
+	tlsConfigs[key] = config
+	tlsConfigsMu.Unlock()
+	return nil
+}
 
-func init() {
-	sql.Register(DriverNameSQL, &orientDriver{})
+// DeregisterTLSConfig removes a TLS config registered with RegisterTLSConfig.
+func DeregisterTLSConfig(key string) {
+	tlsConfigsMu.Lock()
+	delete(tlsConfigs, key)
+	tlsConfigsMu.Unlock()
+}
+
+func getTLSConfig(key string) (*tls.Config, error) {
+	switch key {
+	case "", "false":
+		return nil, nil
+	case "true":
+		return &tls.Config{}, nil
+	case "skip-verify":
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	tlsConfigsMu.RLock()
+	conf, ok := tlsConfigs[key]
+	tlsConfigsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("orientgo: TLS config %q is not registered", key)
+	}
+	return conf, nil
 }
 
 // DialDSN returns a new connection to the database.
@@ -36,24 +70,25 @@ func init() {
 //   or
 //   user@pass:host/db  (default port of 2424 is used)
 //
-// Function is also used for database/sql driver:
-//   db, err := sql.Open("orient", "admin@admin:127.0.0.1/db")
+// Host part may contain a list of cluster nodes, as accepted by Dial.
+// TLS can be enabled with "tls" parameter (see RegisterTLSConfig):
+//   user@pass:host/db?tls=true
+//
+// Other client options can be set with DSN parameters as well:
+//   user@pass:host/db?maxConnections=10&retryCount=3&dialTimeout=5s&handshakeTimeout=1s&readTimeout=30s&maxIdleTime=5m&maxLifetime=1h&pingOnBorrow=true
 func DialDSN(dsn string) (*Database, error) {
-	user, pass, host, port, dbname, err := parseDsn(dsn)
-	if port == "" {
-		port = "2424"
-	}
+	cfg, err := parseDsn(dsn)
 	if err != nil {
 		return nil, err
 	}
-	dbc, err := Dial(net.JoinHostPort(host, port))
+	dbc, err := DialWithOptions(cfg.addr, cfg.opts)
 	if err != nil {
 		return nil, err
 	}
 	// TODO: right now assumes DocumentDB type - pass in on the dsn??
 	//       NOTE: I tried a graphDB with DocumentDB type and it worked, so why is it necesary at all?
 	// TODO: this maybe shouldn't happen in this method -> might do it lazily in Query/Exec methods?
-	db, err := dbc.Open(dbname, DocumentDB, user, pass)
+	db, err := dbc.Open(cfg.dbname, DocumentDB, cfg.user, cfg.pass)
 	if err != nil {
 		dbc.Close()
 		return nil, err
@@ -61,267 +96,133 @@ func DialDSN(dsn string) (*Database, error) {
 	return db, nil
 }
 
-// Implements the Go sql/driver.Driver interface.
-type orientDriver struct{}
-
-// Open implements sql/driver.Driver interface
-// See DialDSN for more info.
-func (d *orientDriver) Open(dsn string) (driver.Conn, error) {
-	glog.V(10).Infoln("OgonoriDriver#Open")
-	return DialDSN(dsn)
+type dsnConfig struct {
+	user, pass string
+	addr       string
+	dbname     string
+	opts       Options
 }
 
-func parseDsn(dsn string) (uname, passw, host, port, dbname string, err error) {
+func parseDsn(dsn string) (*dsnConfig, error) {
 	matches := dsnRx.FindStringSubmatch(dsn)
 	if matches == nil || len(matches) != 5 {
-		return "", "", "", "", "",
-			fmt.Errorf("Unable to parse connection string: %s. Must be of the format: %s",
-				dsn, "uname@passw:ip-or-host/dbname")
-	}
-	toks := strings.Split(matches[3], ":")
-	host = toks[0]
-	if len(toks) > 1 {
-		port = toks[1]
-	}
-	return matches[1], matches[2], host, port, matches[4], nil
-}
-
-// Prepare implements sql/driver.Conn interface
-func (db *Database) Prepare(query string) (driver.Stmt, error) {
-	glog.V(10).Infoln("ogoConn.Prepare: ", query)
-	return &ogonoriStmt{db: db, query: query}, nil
-}
-
-// Begin implements sql/driver.Conn interface
-func (db *Database) Begin() (driver.Tx, error) {
-	glog.V(10).Infoln("ogoConn.Begin")
-	return nil, fmt.Errorf("orientgo: transactions are not supported for now")
-}
-
-// Exec implements sql/driver.Execer interface
-func (db *Database) Exec(cmd string, args []driver.Value) (driver.Result, error) {
-	glog.V(10).Infoln("ogoConn.Exec")
-	if db == nil {
-		return nil, ErrInvalidConn{Msg: "db not initialized in ogonoriConn#Exec"}
-	}
-	var o interface{}
-	err := db.Command(NewSQLCommand(cmd, driverArgs(args)...)).All(&o)
-	if err != nil {
-		return ogonoriResult{-1, -1}, err
-	}
-
-	switch rec := o.(type) {
-	case int32:
-		return ogonoriResult{int64(rec), -1}, nil
-	case int64:
-		return ogonoriResult{int64(rec), -1}, nil
-	case int:
-		return ogonoriResult{int64(rec), -1}, nil
-	case []OIdentifiable:
-		return ogonoriResult{int64(len(rec)), rec[len(rec)-1].GetIdentity().ClusterPos}, nil
-	case OIdentifiable:
-		return ogonoriResult{1, rec.GetIdentity().ClusterPos}, err
-	}
-	return nil, fmt.Errorf("exec with return values is not supported for now, out type: %T", o)
-}
-
-// Query implements sql/driver.Queryer interface
-func (db *Database) Query(query string, args []driver.Value) (driver.Rows, error) {
-	glog.V(10).Infoln("ogoConn.Query")
-	if db == nil {
-		return nil, ErrInvalidConn{Msg: "db not initialized in ogonoriConn#Exec"}
-	}
-	var o interface{}
-	err := db.Command(NewSQLQuery(query, driverArgs(args)...)).All(&o)
-	if err != nil {
-		return nil, err
-	}
-	switch rec := o.(type) {
-	case []OIdentifiable:
-		return newRows(rec)
-	}
-	return nil, fmt.Errorf("query with return values is not supported for now, out type: %T", o)
-}
-
-func driverArgs(args []driver.Value) []interface{} {
-	out := make([]interface{}, len(args))
-	for i, val := range args {
-		glog.V(10).Infof("valarg: %T: %v; isValue=%v\n", val, val, driver.IsValue(val)) // DEBUG
-		switch val.(type) {
-		case string, int64, float64, bool, []byte, time.Time:
-			out[i] = val
-		default:
-			_, file, line, _ := runtime.Caller(0)
-			glog.Warningf("Unexpected type in ogonoriConn#Exec: %T. (%s:%d)", val, file, line)
+		return nil, fmt.Errorf("Unable to parse connection string: %s. Must be of the format: %s",
+			dsn, "uname@passw:ip-or-host/dbname")
+	}
+	cfg := &dsnConfig{user: matches[1], pass: matches[2], addr: matches[3], dbname: matches[4]}
+	if i := strings.Index(cfg.dbname, "?"); i >= 0 {
+		params, err := url.ParseQuery(cfg.dbname[i+1:])
+		if err != nil {
+			return nil, err
 		}
-	}
-	return out
-}
-
-var _ driver.Result = (*ogonoriResult)(nil)
-
-// ogonoriResult implements the sql/driver.Result inteface.
-type ogonoriResult struct {
-	affectedRows int64
-	insertId     int64
-}
-
-// LastInsertId returns the database's auto-generated ID after,
-// for example, an INSERT into a table with primary key.
-func (res ogonoriResult) LastInsertId() (int64, error) {
-	return res.insertId, nil
-}
-
-// RowsAffected returns the number of rows affected by the query.
-func (res ogonoriResult) RowsAffected() (int64, error) {
-	return res.affectedRows, nil
-}
-
-var _ driver.Rows = (*ogonoriRows)(nil)
-
-// ogonoriRows implements the sql/driver.Rows interface.
-type ogonoriRows struct {
-	pos     int // index of next row (doc) to return
-	docs    []OIdentifiable
-	cols    []string
-	fulldoc bool // whether query returned a full document or just properties
-	// TODO: maybe a reference to the appropriate schema is needed here?
-}
-
-func newRows(docs []OIdentifiable) (*ogonoriRows, error) {
-	var cols []string
-	if docs == nil || len(docs) == 0 {
-		cols = []string{}
-		return &ogonoriRows{docs: docs, cols: cols}, nil
-	}
-
-	for i := range docs {
-		if rdoc, ok := docs[i].(*Document); ok {
-			doc, err := rdoc.ToDocument()
+		cfg.dbname = cfg.dbname[:i]
+		for k := range params {
+			v := params.Get(k)
+			switch k {
+			case "tls":
+				cfg.opts.TLSConfig, err = getTLSConfig(v)
+			case "maxConnections":
+				cfg.opts.MaxConnections, err = strconv.Atoi(v)
+			case "retryCount":
+				cfg.opts.RetryCount, err = strconv.Atoi(v)
+			case "dialTimeout":
+				cfg.opts.DialTimeout, err = time.ParseDuration(v)
+			case "handshakeTimeout":
+				cfg.opts.HandshakeTimeout, err = time.ParseDuration(v)
+			case "readTimeout":
+				cfg.opts.ReadTimeout, err = time.ParseDuration(v)
+			case "maxIdleTime":
+				cfg.opts.MaxIdleTime, err = time.ParseDuration(v)
+			case "maxLifetime":
+				cfg.opts.MaxLifetime, err = time.ParseDuration(v)
+			case "pingOnBorrow":
+				cfg.opts.PingOnBorrow, err = strconv.ParseBool(v)
+			case "useToken":
+				cfg.opts.UseToken, err = strconv.ParseBool(v)
+			case "recordCacheSize":
+				cfg.opts.RecordCacheSize, err = strconv.Atoi(v)
+			case "recordFormat":
+				cfg.opts.RecordFormat = v
+			default:
+				err = fmt.Errorf("orientgo: unknown DSN parameter: %q", k)
+			}
 			if err != nil {
 				return nil, err
 			}
-			docs[i] = doc
-		} else {
-			return nil, fmt.Errorf("rows type: %T", docs[i])
-		}
-	}
-
-	var fulldoc bool
-	if doc, ok := docs[0].(*Document); ok {
-		if doc.classname == "" {
-			cols = make([]string, 0, len(doc.FieldNames()))
-			for _, fname := range doc.FieldNames() {
-				cols = append(cols, fname)
-			}
-		} else {
-			fulldoc = true
-			// if Classname is set then the user queried for a full document
-			// not individual properties of a Document/Class
-			cols = []string{doc.classname}
 		}
 	}
-	return &ogonoriRows{docs: docs, cols: cols, fulldoc: fulldoc}, nil
+	return cfg, nil
 }
 
-// Columns returns the names of the columns. The number of
-// columns of the result is inferred from the length of the
-// slice.  If a particular column name isn't known, an empty
-// string should be returned for that entry.
-func (rows *ogonoriRows) Columns() []string {
-	glog.V(10).Infof("ogonoriRows.Columns = %v\n", rows.cols)
-	return rows.cols
+// Driver name for database/sql package
+const DriverNameSQL = "orient"
+
+var (
+	_ driver.Driver      = (*orientDriver)(nil)
+	_ driver.Conn        = (*SQLConn)(nil)
+	_ driver.ConnBeginTx = (*SQLConn)(nil)
+)
+
+func init() {
+	sql.Register(DriverNameSQL, &orientDriver{})
 }
 
-// Next is called to populate the next row of data into
-// the provided slice. The provided slice will be the same
-// size as the Columns() are wide.
-//
-// The dest slice may be populated only with
-// a driver Value type, but excluding string.
-// All string values must be converted to []byte.
-//
-// Next should return io.EOF when there are no more rows.
-func (rows *ogonoriRows) Next(dest []driver.Value) error {
-	glog.V(10).Infoln("ogonoriRows.Next")
-	// TODO: right now I return the entire resultSet as an array, thus all loaded into memory
-	//       it would be better to have obinary.dbCommands provide an iterator based model
-	//       that only needs to read a "row" (Document) at a time
-	if rows.pos >= len(rows.docs) {
-		return io.EOF
-	}
-	// TODO: may need to do a type switch here -> what else can come in from a query in OrientDB
-	//       besides an Document ??
-	currdoc := rows.docs[rows.pos]
-	if doc, ok := currdoc.(*Document); ok {
-		if rows.fulldoc {
-			dest[0] = doc
-		} else {
-			// was a property only query
-			for i := range dest {
-				// TODO: need to check field.Type and see if it is one that can map to Value
-				//       what will I do for types that don't map to Value (e.g., EmbeddedRecord, EmbeddedMap) ??
-				field := doc.GetField(rows.cols[i])
-				if field != nil {
-					dest[i] = field.Value
-				}
-			}
-		}
-	} else {
-		return fmt.Errorf("next on %T", currdoc)
-	}
+// Implements the Go sql/driver.Driver interface.
+type orientDriver struct{}
 
-	rows.pos++
-	// TODO: this is where we need to return any errors that occur
-	return nil
+// Open implements sql/driver.Driver interface
+// See DialDSN for more info.
+func (d *orientDriver) Open(dsn string) (driver.Conn, error) {
+	db, err := DialDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLConn{db: db}, nil
 }
 
-// Close closes the rows iterator.
-func (rows *ogonoriRows) Close() error {
-	glog.V(10).Infoln("ogonoriRows.Close")
-	return nil
+// SQLConn is a connection of database/sql driver. Only transactions are supported for now.
+// Records are added to a transaction started with database/sql by accessing the connection with sql.Conn.Raw:
+//
+//	conn, err := sqldb.Conn(ctx)
+//	stx, err := conn.BeginTx(ctx, nil)
+//	err = conn.Raw(func(c interface{}) error {
+//		return c.(*orient.SQLConn).Tx().CreateRecord(doc)
+//	})
+//	err = stx.Commit()
+type SQLConn struct {
+	db *Database
+	tx *Tx // last transaction started with database/sql
 }
 
-var _ driver.Stmt = (*ogonoriStmt)(nil)
+// Database returns the database of the connection.
+func (c *SQLConn) Database() *Database { return c.db }
 
-// ogonoriStmt implements the Go sql/driver.Stmt interface.
-type ogonoriStmt struct {
-	// TODO: review this - this is how the mysql driver does it
-	db    *Database
-	query string // the SQL query/cmd specified by the user
-}
+// Tx returns the last transaction started on the connection with database/sql, or nil if there were none.
+func (c *SQLConn) Tx() *Tx { return c.tx }
 
-// NumInput returns the number of placeholder parameters.
-func (st *ogonoriStmt) NumInput() int {
-	glog.V(10).Infoln("ogonoriStmt.NumInput")
-	return -1 // -1 means sql package will not "sanity check" arg counts
+// Prepare implements sql/driver.Conn interface
+func (c *SQLConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("orientgo: statements are not supported by database/sql driver for now")
 }
 
-// Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
-func (st *ogonoriStmt) Exec(args []driver.Value) (driver.Result, error) {
-	glog.V(10).Infoln("ogonoriStmt.Exec")
-	if st.db == nil {
-		return nil, ErrInvalidConn{Msg: "obinary.DBClient not initialized in ogonoriStmt#Exec"}
-	}
-	return st.db.Exec(st.query, args)
+// Begin implements sql/driver.Conn interface
+func (c *SQLConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
 }
 
-// Query executes a query that may return rows, such as a SELECT.
-func (st *ogonoriStmt) Query(args []driver.Value) (driver.Rows, error) {
-	glog.V(10).Infoln("ogonoriStmt.Query")
-	if st.db == nil {
-		return nil, ErrInvalidConn{Msg: "obinary.DBClient not initialized in ogonoriStmt#Query"}
+// BeginTx implements sql/driver.ConnBeginTx interface. Isolation levels and read-only transactions are not supported.
+func (c *SQLConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, fmt.Errorf("orientgo: isolation levels are not supported")
+	} else if opts.ReadOnly {
+		return nil, fmt.Errorf("orientgo: read-only transactions are not supported")
 	}
-	return st.db.Query(st.query, args)
+	c.tx = c.db.Begin()
+	return c.tx, nil
 }
 
-// Close closes the statement.
-//
-// As of Go 1.1, a Stmt will not be closed if it's in use by any queries.
-func (st *ogonoriStmt) Close() error {
-	glog.V(10).Info("ogonoriStmt.Close")
-	// nothing to do here since there is no special statement handle in OrientDB
-	// that is referenced by a client driver
-	return nil
+// Close implements sql/driver.Conn interface
+func (c *SQLConn) Close() error {
+	c.db.Close()
+	return c.db.cli.Close()
 }
-*/
@@ -0,0 +1,56 @@
+package orient
+
+import (
+	"context"
+	"time"
+)
+
+// StorageInfo describes storage configuration of a database.
+type StorageInfo struct {
+	Name           string
+	Version        int    // version of storage configuration format
+	DateFormat     string // Java format of DATE values, e.g. yyyy-MM-dd
+	DateTimeFormat string // Java format of DATETIME values, e.g. yyyy-MM-dd HH:mm:ss
+	TimeZone       string // time zone ID, as reported by the server
+	LocaleLanguage string
+	LocaleCountry  string
+	Charset        string
+	Clusters       []ClusterInfo
+}
+
+// Location returns time zone of the database. Local time zone is returned if database time zone is not set
+// or is not known to Go runtime.
+func (s *StorageInfo) Location() *time.Location {
+	if s == nil || s.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// ClusterInfo describes a single cluster of a database.
+type ClusterInfo struct {
+	ID     int16
+	Name   string
+	Type   string // cluster implementation, e.g. "paginated"; empty if not reported by the server
+	Status string // ONLINE or OFFLINE; empty if not reported by the server
+	Class  string // name of the class the cluster belongs to; empty if cluster is not used by any class
+}
+
+// StorageInfo returns storage configuration of the database, including a list of all clusters.
+// Configuration is reloaded from the server on each call.
+func (db *Database) StorageInfo() (*StorageInfo, error) {
+	return db.StorageInfoContext(context.Background())
+}
+
+// StorageInfoContext is like StorageInfo, but allows to cancel the request with a context.
+func (db *Database) StorageInfoContext(ctx context.Context) (info *StorageInfo, err error) {
+	err = db.withConn(ctx, true, func(conn DBSession) error {
+		info, err = conn.StorageInfoContext(ctx)
+		return err
+	})
+	return
+}
@@ -0,0 +1,64 @@
+package orient
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// SessionToken is a token of stateless database session. It is returned by the server on database open
+// if Options.UseToken is set, and can be used to open new sessions on other connections without credentials.
+type SessionToken struct {
+	SessionID int32
+	Token     []byte
+}
+
+// IsZero checks if session is not token-based.
+func (t SessionToken) IsZero() bool { return len(t.Token) == 0 }
+
+// dbAuth holds credentials for opening new database sessions.
+// If token sessions are enabled, credentials are dropped as soon as the first token is received.
+type dbAuth struct {
+	mu    sync.Mutex
+	user  string
+	pass  string
+	token SessionToken
+}
+
+// open opens a new database session on conn, using session token if it's available.
+func (a *dbAuth) open(ctx context.Context, conn DBConnection, name string, dbType DatabaseType) (DBSession, error) {
+	a.mu.Lock()
+	user, pass, tok := a.user, a.pass, a.token
+	a.mu.Unlock()
+	if !tok.IsZero() {
+		return conn.OpenTokenContext(ctx, name, dbType, tok)
+	}
+	ds, err := conn.OpenContext(ctx, name, dbType, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	a.update(ds.Token())
+	return ds, nil
+}
+
+// update stores a new (or renewed) session token.
+func (a *dbAuth) update(tok SessionToken) {
+	if tok.IsZero() {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if bytes.Equal(a.token.Token, tok.Token) {
+		return
+	}
+	a.token = tok
+	a.user, a.pass = "", ""
+}
+
+// Token returns current session token of the database. It is empty if token sessions are disabled
+// (see Options.UseToken) or not supported by the server.
+func (db *Database) Token() SessionToken {
+	db.auth.mu.Lock()
+	defer db.auth.mu.Unlock()
+	return db.auth.token
+}
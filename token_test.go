@@ -0,0 +1,55 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type tokenSession struct {
+	DBSession
+	tok SessionToken
+}
+
+func (s tokenSession) Token() SessionToken { return s.tok }
+
+// tokenConn issues a token on password login and accepts only that token.
+type tokenConn struct {
+	fakeConn
+	logins int
+}
+
+func (c *tokenConn) OpenContext(ctx context.Context, name string, dbType DatabaseType, user, pass string) (DBSession, error) {
+	if user != "admin" || pass != "secret" {
+		return nil, fmt.Errorf("wrong credentials")
+	}
+	c.logins++
+	return tokenSession{tok: SessionToken{SessionID: 1, Token: []byte("tok")}}, nil
+}
+
+func (c *tokenConn) OpenTokenContext(ctx context.Context, name string, dbType DatabaseType, tok SessionToken) (DBSession, error) {
+	if string(tok.Token) != "tok" {
+		return nil, fmt.Errorf("wrong token")
+	}
+	return tokenSession{tok: tok}, nil
+}
+
+func TestDBAuthToken(t *testing.T) {
+	ctx := context.Background()
+	conn := &tokenConn{}
+	a := &dbAuth{user: "admin", pass: "secret"}
+	for i := 0; i < 3; i++ {
+		if _, err := a.open(ctx, conn, "db", DocumentDB); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conn.logins != 1 {
+		t.Fatalf("expected a single password login, got %d", conn.logins)
+	} else if a.user != "" || a.pass != "" {
+		t.Fatal("credentials were not dropped")
+	}
+	a.update(SessionToken{SessionID: 1, Token: []byte("renewed")})
+	if _, err := a.open(ctx, conn, "db", DocumentDB); err == nil {
+		t.Fatal("renewed token was not used")
+	}
+}
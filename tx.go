@@ -0,0 +1,203 @@
+package orient
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// TxOpType is a type of record operation inside a transaction.
+type TxOpType byte
+
+// List of record operations, as defined by ORecordOperation
+const (
+	TxOpUpdate TxOpType = 1
+	TxOpDelete TxOpType = 2
+	TxOpCreate TxOpType = 3
+)
+
+func (t TxOpType) String() string {
+	switch t {
+	case TxOpUpdate:
+		return "update"
+	case TxOpDelete:
+		return "delete"
+	case TxOpCreate:
+		return "create"
+	}
+	return fmt.Sprintf("TxOpType(%d)", int(t))
+}
+
+// TxOp is a single record operation buffered by a transaction.
+type TxOp struct {
+	Type    TxOpType
+	RID     RID     // record RID; temporary (negative position) for created records
+	Version int     // record version for updates and deletes
+	Record  ORecord // nil for deletes
+}
+
+// ErrTxDone is returned by any operation that is performed on a transaction that has already been committed or rolled back.
+var ErrTxDone = fmt.Errorf("orientgo: transaction has already been committed or rolled back")
+
+// firstTempPos is a cluster position of the first record created in a transaction.
+// Positions below clusterPosInvalid are treated as temporary by the server.
+const firstTempPos = clusterPosInvalid - 1
+
+var lastTxID int32
+
+var _ driver.Tx = (*Tx)(nil)
+
+// Tx is a client-side transaction. It buffers record changes and sends them to the server in a single request on Commit.
+//
+// Records created in transaction get a temporary RID (see RID.IsTemporary) that can be used to link them to other
+// records of the same transaction. RIDs and versions are replaced with server-assigned ones after Commit.
+//
+// Tx also implements sql/driver.Tx interface (see SQLConn).
+type Tx struct {
+	db *Database
+
+	mu   sync.Mutex
+	ops  []TxOp
+	byID map[RID]int // RID -> index in ops
+	next int64       // next temporary position
+	done bool
+}
+
+// Begin starts a new transaction. No requests are sent to the server until Commit is called.
+func (db *Database) Begin() *Tx {
+	return &Tx{db: db, byID: make(map[RID]int), next: firstTempPos}
+}
+
+// add appends an operation to the transaction or merges it with a previous operation on the same record.
+func (tx *Tx) add(op TxOp) {
+	i, ok := tx.byID[op.RID]
+	if !ok {
+		tx.byID[op.RID] = len(tx.ops)
+		tx.ops = append(tx.ops, op)
+		return
+	}
+	prev := &tx.ops[i]
+	switch {
+	case prev.Type == TxOpCreate && op.Type == TxOpUpdate:
+		prev.Record = op.Record // record will be serialized on commit, so it's still a create
+	case prev.Type == TxOpCreate && op.Type == TxOpDelete:
+		prev.Type, prev.Record = 0, nil // record never reaches the server
+		delete(tx.byID, op.RID)
+	default:
+		*prev = op
+	}
+}
+
+// CreateRecord adds a new record to the transaction and assigns a temporary RID to it.
+func (tx *Tx) CreateRecord(rec ORecord) error {
+	rid := rec.GetIdentity()
+	if rid.IsPersistent() {
+		return fmt.Errorf("record is already persistent: %v", rid)
+	}
+	if doc, ok := rec.(*Document); ok && rid.ClusterID < 0 {
+		if cur := tx.db.GetCurDB(); cur != nil {
+			if oclass, ok := cur.Classes[doc.ClassName()]; ok {
+				rid.ClusterID = int16(oclass.DefaultClusterId)
+			}
+		}
+	}
+	if rid.ClusterID < 0 {
+		return fmt.Errorf("cannot find cluster for a new record; set class name or RID with NewRIDInCluster")
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	rid.ClusterPos = tx.next
+	tx.next--
+	rec.SetRID(rid)
+	tx.add(TxOp{Type: TxOpCreate, RID: rid, Record: rec})
+	return nil
+}
+
+// UpdateRecord adds a record update to the transaction. Record content is serialized on Commit.
+func (tx *Tx) UpdateRecord(rec ORecord) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	rid := rec.GetIdentity()
+	if !rid.IsPersistent() && !rid.IsTemporary() {
+		return fmt.Errorf("record is not persistent: %v", rid)
+	}
+	tx.add(TxOp{Type: TxOpUpdate, RID: rid, Version: rec.Version(), Record: rec})
+	return nil
+}
+
+// DeleteRecordByRID adds a record removal to the transaction.
+func (tx *Tx) DeleteRecordByRID(rid RID, recVersion int) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.add(TxOp{Type: TxOpDelete, RID: rid, Version: recVersion})
+	return nil
+}
+
+// Ops returns a list of operations buffered by the transaction.
+func (tx *Tx) Ops() []TxOp {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.pending()
+}
+
+func (tx *Tx) pending() []TxOp {
+	ops := make([]TxOp, 0, len(tx.ops))
+	for _, op := range tx.ops {
+		if op.Type != 0 {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// Commit sends all buffered operations to the server. Temporary RIDs and versions of records are
+// updated to server-assigned values.
+//
+// Transaction cannot be used after Commit, even if it fails.
+func (tx *Tx) Commit() error {
+	return tx.CommitContext(context.Background())
+}
+
+// CommitContext is like Commit, but allows to cancel the request with a context.
+func (tx *Tx) CommitContext(ctx context.Context) error {
+	tx.mu.Lock()
+	if tx.done {
+		tx.mu.Unlock()
+		return ErrTxDone
+	}
+	tx.done = true
+	ops := tx.pending()
+	tx.mu.Unlock()
+	if len(ops) == 0 {
+		return nil
+	}
+	id := int(atomic.AddInt32(&lastTxID, 1))
+	err := tx.db.withConn(ctx, false, func(conn DBSession) error {
+		return conn.CommitContext(ctx, id, ops)
+	})
+	return convertError(err)
+}
+
+// Rollback discards all buffered operations. Since nothing is sent to the server before Commit,
+// it never returns errors other than ErrTxDone.
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.ops, tx.byID = nil, nil
+	return nil
+}
@@ -0,0 +1,146 @@
+package orient
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+// txSession emulates server-side RID assignment for committed transactions.
+type txSession struct {
+	testSession
+	ops []TxOp
+}
+
+func (s *txSession) GetCurDB() *ODatabase {
+	return &ODatabase{Classes: map[string]*OClass{"V": {Name: "V", DefaultClusterId: 9}}}
+}
+
+func (s *txSession) CommitContext(ctx context.Context, txID int, ops []TxOp) error {
+	s.ops = ops
+	for i, op := range ops {
+		if op.Record == nil {
+			continue
+		}
+		rid := op.RID
+		if op.Type == TxOpCreate {
+			rid.ClusterPos = int64(i)
+		}
+		op.Record.Fill(rid, op.Version+1, nil)
+	}
+	return nil
+}
+
+func TestTxCommit(t *testing.T) {
+	sess := &txSession{}
+	db := newTestDB(t, func() DBSession { return sess })
+
+	tx := db.Begin()
+	v := NewDocument("V")
+	if err := tx.CreateRecord(v); err != nil {
+		t.Fatal(err)
+	} else if rid := v.GetIdentity(); !rid.IsTemporary() || rid.ClusterID != 9 {
+		t.Fatalf("expected temporary rid, got %v", rid)
+	}
+	b := NewBytesRecord()
+	b.SetRID(NewRIDInCluster(3))
+	if err := tx.CreateRecord(b); err != nil {
+		t.Fatal(err)
+	} else if b.GetIdentity() == v.GetIdentity() {
+		t.Fatal("temporary rids are not unique")
+	}
+	if err := tx.UpdateRecord(v); err != nil { // merged with create
+		t.Fatal(err)
+	}
+	if err := tx.DeleteRecordByRID(b.GetIdentity(), 0); err != nil { // cancels create
+		t.Fatal(err)
+	}
+	u := NewBytesRecord()
+	u.Fill(NewRID(3, 5), 2, nil)
+	if err := tx.UpdateRecord(u); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DeleteRecordByRID(NewRID(3, 6), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.ops) != 3 {
+		t.Fatalf("unexpected operations: %v", sess.ops)
+	} else if sess.ops[0].Type != TxOpCreate || sess.ops[1].Type != TxOpUpdate || sess.ops[2].Type != TxOpDelete {
+		t.Fatalf("unexpected operations: %v", sess.ops)
+	}
+	if rid := v.GetIdentity(); !rid.IsPersistent() {
+		t.Fatalf("rid was not updated: %v", rid)
+	} else if u.Version() != 3 {
+		t.Fatalf("version was not updated: %d", u.Version())
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Fatalf("expected ErrTxDone, got %v", err)
+	} else if err = tx.Rollback(); err != ErrTxDone {
+		t.Fatalf("expected ErrTxDone, got %v", err)
+	}
+}
+
+// sqlConnector opens connections to a single database for sql.OpenDB.
+type sqlConnector struct {
+	db *Database
+}
+
+func (c sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &SQLConn{db: c.db}, nil
+}
+
+func (c sqlConnector) Driver() driver.Driver { return &orientDriver{} }
+
+func TestSQLTxCommit(t *testing.T) {
+	sess := &txSession{}
+	db := newTestDB(t, func() DBSession { return sess })
+	sdb := sql.OpenDB(sqlConnector{db: db})
+	defer sdb.Close()
+	ctx := context.Background()
+	conn, err := sdb.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewDocument("V")
+	err = conn.Raw(func(c interface{}) error {
+		return c.(*SQLConn).Tx().CreateRecord(v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stx.Commit(); err != nil {
+		t.Fatal(err)
+	} else if len(sess.ops) != 1 || sess.ops[0].Record != v {
+		t.Fatalf("unexpected operations: %v", sess.ops)
+	} else if rid := v.GetIdentity(); !rid.IsPersistent() {
+		t.Fatalf("rid was not updated: %v", rid)
+	}
+	if err = stx.Commit(); err != sql.ErrTxDone {
+		t.Fatalf("expected ErrTxDone, got %v", err)
+	}
+
+	if _, err = conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err == nil {
+		t.Fatal("read-only transactions must not be supported")
+	}
+	if stx, err = conn.BeginTx(ctx, nil); err != nil {
+		t.Fatal(err)
+	} else if err = stx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	err = conn.Raw(func(c interface{}) error {
+		return c.(*SQLConn).Tx().CreateRecord(NewDocument("V"))
+	})
+	if err != ErrTxDone {
+		t.Fatalf("expected ErrTxDone, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build go1.23
+
+package orient
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// Load loads a record by RID and converts it to T, which is usually a struct with orient tags (see FieldTagName).
+// ErrRecordNotFound is returned if the record does not exist.
+//
+//	cat, err := orient.Load[Cat](db, rid)
+func Load[T any](db *Database, rid RID) (T, error) {
+	return LoadContext[T](context.Background(), db, rid)
+}
+
+// LoadContext is like Load, but allows to cancel the request with a context.
+func LoadContext[T any](ctx context.Context, db *Database, rid RID) (out T, err error) {
+	rec, err := db.GetRecordByRIDContext(ctx, rid, DefaultFetchPlan, false)
+	if err != nil {
+		return out, err
+	} else if rec == nil {
+		return out, newErrRecordNotFound(UnknownException{
+			Class:   "com.orientechnologies.orient.core.exception.ORecordNotFoundException",
+			Message: fmt.Sprintf("The record with id '%v' was not found", rid),
+		})
+	}
+	err = convertResult(&out, rec)
+	return out, err
+}
+
+// Query runs SQL query with Database.Query and returns an iterator over its results converted to T.
+// Conversion or query error is returned as the last element of the sequence. Results are closed when iteration
+// stops, so it's safe to break the loop early.
+//
+//	for cat, err := range orient.Query[Cat](db, "SELECT FROM Cat WHERE age > ?", 10) {
+//		if err != nil {
+//			// ...
+//		}
+//		// ...
+//	}
+func Query[T any](db *Database, sql string, params ...interface{}) iter.Seq2[T, error] {
+	return QueryContext[T](context.Background(), db, sql, params...)
+}
+
+// QueryContext is like Query, but allows to cancel the request with a context.
+func QueryContext[T any](ctx context.Context, db *Database, sql string, params ...interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		results := db.QueryContext(ctx, sql, params...)
+		for {
+			var v T
+			if !results.Next(&v) {
+				break
+			} else if !yield(v, nil) {
+				results.Close()
+				return
+			}
+		}
+		if err := results.Close(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// Save stores a struct as a document. Record is created if v has no persistent RID, and updated otherwise.
+// Metadata fields of v (@rid, @version) are updated on success; class of new records is taken from @class field.
+//
+//	cat := Cat{Class: "Cat", Name: "Tom"}
+//	err := orient.Save(db, &cat) // cat.RID is set
+func Save[T any](db *Database, v *T) error {
+	return SaveContext(context.Background(), db, v)
+}
+
+// SaveContext is like Save, but allows to cancel the request with a context.
+func SaveContext[T any](ctx context.Context, db *Database, v *T) error {
+	doc := NewEmptyDocument()
+	if err := doc.From(v); err != nil {
+		return err
+	}
+	if doc.RID.IsPersistent() {
+		return db.UpdateRecordContext(ctx, doc)
+	}
+	return db.CreateRecordContext(ctx, doc)
+}
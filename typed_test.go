@@ -0,0 +1,105 @@
+//go:build go1.23
+
+package orient
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// typedSession keeps created records as documents and serves them back to loads and queries.
+type typedSession struct {
+	recordSession
+	docs map[RID]*Document
+}
+
+func (s *typedSession) CreateRecordContext(ctx context.Context, rec ORecord) error {
+	if err := s.recordSession.CreateRecordContext(ctx, rec); err != nil {
+		return err
+	}
+	s.docs[rec.GetIdentity()] = rec.(*Document)
+	return nil
+}
+
+func (s *typedSession) UpdateRecordContext(ctx context.Context, rec ORecord) error {
+	if err := s.recordSession.UpdateRecordContext(ctx, rec); err != nil {
+		return err
+	}
+	s.docs[rec.GetIdentity()] = rec.(*Document)
+	return nil
+}
+
+func (s *typedSession) GetRecordByRIDContext(ctx context.Context, rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, error) {
+	if doc, ok := s.docs[rid]; ok {
+		return doc, nil
+	}
+	return nil, nil
+}
+
+func (s *typedSession) CommandContext(ctx context.Context, cmd CustomSerializable) (interface{}, error) {
+	out := []OIdentifiable{}
+	for i := 0; i < len(s.docs); i++ {
+		out = append(out, s.docs[NewRID(9, int64(i))])
+	}
+	return out, nil
+}
+
+func TestTypedAPI(t *testing.T) {
+	sess := &typedSession{recordSession: recordSession{recs: make(map[RID]int)}, docs: make(map[RID]*Document)}
+	db := newTestDB(t, func() DBSession { return sess })
+
+	items := []metaItem{{Class: "Item", Name: "one"}, {Class: "Item", Name: "two"}}
+	for i := range items {
+		if err := Save(db, &items[i]); err != nil {
+			t.Fatal(err)
+		} else if items[i].ID != NewRID(9, int64(i)) || items[i].Vers != 1 {
+			t.Fatalf("metadata was not written back: %+v", items[i])
+		}
+	}
+	items[0].Name = "first"
+	if err := Save(db, &items[0]); err != nil {
+		t.Fatal(err)
+	} else if items[0].Vers != 2 || len(sess.docs) != 2 {
+		t.Fatalf("record was not updated: %+v", items[0])
+	}
+
+	item, err := Load[metaItem](db, NewRID(9, 1))
+	if err != nil {
+		t.Fatal(err)
+	} else if item != items[1] {
+		t.Fatalf("wrong record: %+v vs %+v", item, items[1])
+	}
+	var nerr ErrRecordNotFound
+	if _, err = Load[metaItem](db, NewRID(9, 5)); !errors.As(err, &nerr) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if nerr.RID != NewRID(9, 5) || nerr.ExcMessage() != "The record with id '#9:5' was not found" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for item, err := range Query[metaItem](db, "SELECT FROM Item") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, item.Name)
+	}
+	if len(names) != 2 || names[0] != "first" || names[1] != "two" {
+		t.Fatalf("unexpected results: %q", names)
+	}
+	for _, err := range Query[metaItem](db, "SELECT FROM Item") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	var errs int
+	for _, err := range Query[int](db, "SELECT FROM Item") {
+		if err != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Fatalf("conversion error must be returned once, got %d", errs)
+	}
+}
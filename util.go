@@ -1,6 +1,7 @@
 package orient
 
 import (
+	"reflect"
 	"unicode"
 )
 
@@ -10,3 +11,13 @@ func isExported(s string) bool {
 	}
 	return unicode.IsUpper(([]rune(s))[0])
 }
+
+// isEmptyValue checks if v is omitted by omitempty option: it's either a zero value of its type,
+// or an empty slice or map.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}